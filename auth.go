@@ -8,6 +8,8 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"sync"
@@ -45,48 +47,168 @@ const (
 // refresh under the same rule. The returned source is safe for concurrent use;
 // concurrent Token calls that find the cache stale collapse into a single
 // upstream fetch.
-func ReuseTokenSourceWithSkew(t *oauth2.Token, src oauth2.TokenSource, skew time.Duration) oauth2.TokenSource {
-	if skew <= 0 {
-		return oauth2.ReuseTokenSource(t, src)
+//
+// Options such as WithTokenStore extend the cache; when any option is given
+// the wrapper is always used, and a non-positive skew refreshes only once the
+// token has expired.
+func ReuseTokenSourceWithSkew(t *oauth2.Token, src oauth2.TokenSource, skew time.Duration, opts ...ReuseTokenSourceOpt) oauth2.TokenSource {
+	if skew <= 0 && len(opts) == 0 {
+		return &reuseTokenSource{
+			TokenSource: oauth2.ReuseTokenSource(t, src),
+			src:         src,
+		}
 	}
-	return &reuseTokenSourceWithSkew{
-		t:    t,
-		src:  src,
-		skew: skew,
+	return newReuseTokenSourceWithSkew(t, src, skew, opts...)
+}
+
+// newReuseTokenSourceWithSkew builds the skew-aware cache unconditionally,
+// for callers that need to reach its state, such as InstallationCache.
+func newReuseTokenSourceWithSkew(t *oauth2.Token, src oauth2.TokenSource, skew time.Duration, opts ...ReuseTokenSourceOpt) *reuseTokenSourceWithSkew {
+	r := &reuseTokenSourceWithSkew{
+		t:      t,
+		src:    src,
+		skew:   max(skew, 0),
+		logger: discardLogger,
+		clock:  defaultClock,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
+}
+
+// reuseTokenSource is the zero-skew form of ReuseTokenSourceWithSkew. It
+// delegates to oauth2.ReuseTokenSource and only remembers the wrapped source
+// so helpers such as ApplicationKeyFingerprint can still reach it.
+type reuseTokenSource struct {
+	oauth2.TokenSource
+	src oauth2.TokenSource
 }
 
 type reuseTokenSourceWithSkew struct {
-	mu   sync.Mutex
-	t    *oauth2.Token
+	// refreshMu serializes refreshes, which may wait on the store lock or a
+	// throttled mint; mu only guards t and gen, so it is never held while
+	// waiting.
+	refreshMu sync.Mutex
+	mu        sync.Mutex
+	t         *oauth2.Token
+	// gen counts invalidations, so a refresh started before one does not
+	// cache its result.
+	gen  uint64
 	src  oauth2.TokenSource
 	skew time.Duration
+
+	// store and key are set by WithTokenStore.
+	store TokenStore
+	key   TokenKey
+
+	hooks  Hooks
+	logger *slog.Logger
+	clock  Clock
+
+	// installationID tags the cache events of installation token caches.
+	installationID int64
 }
 
 // Token returns the cached token if it is still valid beyond the configured
 // skew, otherwise it calls the underlying source and caches the result.
+// While another caller refreshes, a token that has not expired yet is served
+// instead of waiting for the refresh.
 func (r *reuseTokenSourceWithSkew) Token() (*oauth2.Token, error) {
+	t, _ := r.current()
+	if r.fresh(t) {
+		r.hooks.lookup(CacheEvent{InstallationID: r.installationID, Hit: true})
+		return t, nil
+	}
+	if !r.refreshMu.TryLock() {
+		if r.valid(t) {
+			r.hooks.lookup(CacheEvent{InstallationID: r.installationID, Hit: true})
+			return t, nil
+		}
+		r.refreshMu.Lock()
+	}
+	defer r.refreshMu.Unlock()
+
+	// Another caller may have refreshed while this one waited.
+	t, gen := r.current()
+	if r.fresh(t) {
+		r.hooks.lookup(CacheEvent{InstallationID: r.installationID, Hit: true})
+		return t, nil
+	}
+	if r.store != nil {
+		t, hit, err := r.tokenFromStore(gen)
+		r.hooks.lookup(CacheEvent{InstallationID: r.installationID, Hit: hit})
+		return t, err
+	}
+	r.hooks.lookup(CacheEvent{InstallationID: r.installationID})
+	return r.mint(gen)
+}
+
+// current returns the cached token and the invalidation generation.
+func (r *reuseTokenSourceWithSkew) current() (*oauth2.Token, uint64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if r.valid() {
-		return r.t, nil
+	return r.t, r.gen
+}
+
+// cache stores t unless the cache was invalidated since generation gen.
+func (r *reuseTokenSourceWithSkew) cache(t *oauth2.Token, gen uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen == gen {
+		r.t = t
 	}
+}
+
+// mint fetches a token from the underlying source and caches it. The caller
+// holds r.refreshMu.
+func (r *reuseTokenSourceWithSkew) mint(gen uint64) (*oauth2.Token, error) {
+	start := r.clock.Now()
 	t, err := r.src.Token()
+	ev := RefreshEvent{
+		InstallationID:     r.installationID,
+		Duration:           r.clock.Now().Sub(start),
+		Attempts:           1,
+		Err:                err,
+		ErrorClass:         ClassifyError(err),
+		RateLimitRemaining: -1,
+	}
+	if err == nil {
+		ev.Expiry = t.Expiry
+	}
+	r.hooks.fire(ev)
 	if err != nil {
 		return nil, err
 	}
-	r.t = t
+	r.cache(t, gen)
 	return t, nil
 }
 
-func (r *reuseTokenSourceWithSkew) valid() bool {
-	if r.t == nil || r.t.AccessToken == "" {
+// valid reports whether t has not expired yet, ignoring the skew.
+func (r *reuseTokenSourceWithSkew) valid(t *oauth2.Token) bool {
+	if t == nil || t.AccessToken == "" {
 		return false
 	}
-	if r.t.Expiry.IsZero() {
+	return t.Expiry.IsZero() || t.Expiry.After(r.clock.Now())
+}
+
+// fresh reports whether t can be served without a refresh.
+func (r *reuseTokenSourceWithSkew) fresh(t *oauth2.Token) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.Expiry.IsZero() {
 		return true
 	}
-	return time.Until(r.t.Expiry) > r.skew
+	return t.Expiry.Sub(r.clock.Now()) > r.skew
+}
+
+// invalidate drops the cached token so the next Token call refreshes it.
+func (r *reuseTokenSourceWithSkew) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.t = nil
+	r.gen++
 }
 
 // Identifier constrains GitHub App identifiers to int64 (App ID) or string (Client ID).
@@ -104,6 +226,8 @@ type applicationTokenSource struct {
 	signer     crypto.Signer
 	expiration time.Duration
 	skew       time.Duration
+	hooks      Hooks
+	clock      Clock
 }
 
 // ApplicationTokenOpt is a functional option for configuring an applicationTokenSource.
@@ -217,11 +341,17 @@ func newApplicationTokenSource(issuer string, signer crypto.Signer, opts ...Appl
 		signer:     signer,
 		expiration: DefaultApplicationTokenExpiration,
 		skew:       DefaultExpirySkew,
+		clock:      defaultClock,
 	}
 	for _, opt := range opts {
 		opt(t)
 	}
-	return ReuseTokenSourceWithSkew(nil, t, t.skew)
+
+	reuseOpts := reuseClockOpts(t.clock)
+	if t.hooks.OnCacheLookup != nil {
+		reuseOpts = append(reuseOpts, withCacheHooks(0, t.hooks))
+	}
+	return ReuseTokenSourceWithSkew(nil, t, t.skew, reuseOpts...)
 }
 
 // Token generates a GitHub App JWT with required claims: iat, exp, iss, and alg.
@@ -229,8 +359,26 @@ func newApplicationTokenSource(issuer string, signer crypto.Signer, opts ...Appl
 // Signing is routed through the configured crypto.Signer.
 // Generated JWTs can be used with "Authorization: Bearer" header for GitHub API requests.
 func (t *applicationTokenSource) Token() (*oauth2.Token, error) {
+	start := t.clock.Now()
+	token, err := t.sign()
+	ev := RefreshEvent{
+		Duration:           t.clock.Now().Sub(start),
+		Attempts:           1,
+		Err:                err,
+		ErrorClass:         ClassifyError(err),
+		RateLimitRemaining: -1,
+	}
+	if err == nil {
+		ev.Expiry = token.Expiry
+	}
+	t.hooks.fire(ev)
+	return token, err
+}
+
+// sign builds and signs a new App JWT.
+func (t *applicationTokenSource) sign() (*oauth2.Token, error) {
 	// To protect against clock drift, set the issuance time 60 seconds in the past.
-	now := time.Now().Add(-60 * time.Second)
+	now := t.clock.Now().Add(-60 * time.Second)
 	expiresAt := now.Add(t.expiration)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
@@ -348,7 +496,8 @@ func WithContext(ctx context.Context) InstallationTokenSourceOpt {
 // Retry-After or x-ratelimit-reset (capped at 60s, honoring ctx cancellation)
 // and retries once. Subsequent failures bubble up unchanged. On a terminal
 // throttle the returned error wraps ErrRateLimited so callers can branch with
-// errors.Is.
+// errors.Is; so does the error of a retry sleep cut short by ctx, which also
+// wraps ctx.Err().
 //
 // Disable this when the caller implements its own backoff or when deterministic
 // latency matters more than transient rate-limit resilience.
@@ -381,6 +530,10 @@ type installationTokenSource struct {
 	client *githubClient
 	opts   *InstallationTokenOptions
 	skew   time.Duration
+	store  TokenStore
+	hooks  Hooks
+	logger *slog.Logger
+	clock  Clock
 
 	// configErr records the first invalid-configuration error encountered while
 	// applying options (e.g. an unparseable base URL or a nil HTTP client). It is
@@ -407,6 +560,25 @@ func (t *installationTokenSource) setConfigErr(err error) {
 //
 // See https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-an-installation-access-token-for-a-github-app
 func NewInstallationTokenSource(id int64, src oauth2.TokenSource, opts ...InstallationTokenSourceOpt) oauth2.TokenSource {
+	i := newInstallationTokenSource(id, src, opts...)
+	return ReuseTokenSourceWithSkew(nil, i, i.skew, i.reuseOpts()...)
+}
+
+// reuseOpts returns the options of the token cache wrapping t.
+func (t *installationTokenSource) reuseOpts() []ReuseTokenSourceOpt {
+	opts := reuseClockOpts(t.clock)
+	if t.store != nil {
+		opts = append(opts, WithTokenStore(t.store, t.storeKey()), withReuseLogger(t.logger))
+	}
+	if t.hooks.OnCacheLookup != nil {
+		opts = append(opts, withCacheHooks(t.id, t.hooks))
+	}
+	return opts
+}
+
+// newInstallationTokenSource builds the uncached installation token source
+// shared by NewInstallationTokenSource and Validate.
+func newInstallationTokenSource(id int64, src oauth2.TokenSource, opts ...InstallationTokenSourceOpt) *installationTokenSource {
 	ctx := context.Background()
 
 	httpClient := cleanHTTPClient()
@@ -421,13 +593,15 @@ func NewInstallationTokenSource(id int64, src oauth2.TokenSource, opts ...Instal
 		src:    src,
 		client: newGitHubClient(httpClient),
 		skew:   DefaultExpirySkew,
+		logger: discardLogger,
+		clock:  defaultClock,
 	}
 
 	for _, opt := range opts {
 		opt(i)
 	}
 
-	return ReuseTokenSourceWithSkew(nil, i, i.skew)
+	return i
 }
 
 // Token generates a new GitHub App installation token for authenticating as a GitHub App installation.
@@ -436,7 +610,23 @@ func (t *installationTokenSource) Token() (*oauth2.Token, error) {
 		return nil, t.configErr
 	}
 
-	token, err := t.client.createInstallationToken(t.ctx, t.id, t.opts)
+	start := t.clock.Now()
+	token, stats, err := t.client.mintInstallationToken(t.ctx, t.id, t.opts)
+	ev := RefreshEvent{
+		InstallationID:     t.id,
+		Duration:           t.clock.Now().Sub(start),
+		Attempts:           stats.attempts,
+		Err:                err,
+		ErrorClass:         ClassifyError(err),
+		StatusCode:         stats.statusCode,
+		RequestID:          stats.requestID,
+		RateLimitRemaining: stats.rateLimitRemaining,
+	}
+	if err == nil {
+		ev.Expiry = token.ExpiresAt
+	}
+	t.hooks.fire(ev)
+	t.logRefresh(ev)
 	if err != nil {
 		return nil, err
 	}
@@ -462,12 +652,33 @@ type personalAccessTokenSource struct {
 // The provided token should be a valid GitHub personal access token (classic or fine-grained).
 // This token source returns the same token value for all Token() calls without expiration,
 // making it suitable for long-lived authentication scenarios.
+//
+// The token is not inspected; use NewValidatedPersonalAccessTokenSource to
+// reject malformed tokens up front.
 func NewPersonalAccessTokenSource(token string) oauth2.TokenSource {
 	return &personalAccessTokenSource{
 		token: token,
 	}
 }
 
+// NewValidatedPersonalAccessTokenSource is NewPersonalAccessTokenSource for
+// callers that want a bad token rejected at startup rather than by GitHub
+// with a 401. The token is checked offline with ParseToken: a truncated,
+// mistyped or placeholder token returns an error wrapping ErrMalformedToken,
+// and a well-formed token that is not a classic (ghp_) or fine-grained
+// (github_pat_) personal access token, such as an installation or OAuth
+// token, returns an error wrapping ErrNotPersonalAccessToken.
+func NewValidatedPersonalAccessTokenSource(token string) (oauth2.TokenSource, error) {
+	kind, err := ParseToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid personal access token: %w", err)
+	}
+	if kind != TokenKindPersonalAccess && kind != TokenKindFineGrainedPersonalAccess {
+		return nil, fmt.Errorf("%w: got a %s token", ErrNotPersonalAccessToken, kind)
+	}
+	return NewPersonalAccessTokenSource(token), nil
+}
+
 // Token returns the configured personal access token as an OAuth2 token.
 // The returned token has no expiry time since personal access tokens
 // remain valid until manually revoked or expired by GitHub.
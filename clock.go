@@ -0,0 +1,66 @@
+package githubauth
+
+import "time"
+
+// Clock is the source of time used by the token sources of this package: JWT
+// issuance and expiry, cache freshness and skew, Retry-After and
+// X-RateLimit-Reset handling, and the sleep before a throttle retry. Inject a
+// fake with WithClock, WithInstallationClock or WithReuseClock to test expiry
+// and retry behavior without real sleeps.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has
+	// elapsed, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// defaultClock is used when no Clock is configured.
+var defaultClock Clock = systemClock{}
+
+// WithClock sets the Clock used to stamp the iat and exp claims of App JWTs
+// and to decide when the cached JWT is refreshed.
+func WithClock(c Clock) ApplicationTokenOpt {
+	return func(a *applicationTokenSource) {
+		if c != nil {
+			a.clock = c
+		}
+	}
+}
+
+// WithInstallationClock sets the Clock used to decide when the cached
+// installation token is refreshed, to interpret Retry-After and
+// X-RateLimit-Reset headers and to wait before a throttle retry.
+func WithInstallationClock(c Clock) InstallationTokenSourceOpt {
+	return func(i *installationTokenSource) {
+		if c != nil {
+			i.clock = c
+			i.client.clock = c
+		}
+	}
+}
+
+// WithReuseClock sets the Clock used by ReuseTokenSourceWithSkew to decide
+// whether the cached token is still fresh.
+func WithReuseClock(c Clock) ReuseTokenSourceOpt {
+	return func(r *reuseTokenSourceWithSkew) {
+		if c != nil {
+			r.clock = c
+		}
+	}
+}
+
+// reuseClockOpts returns the option propagating c to a token cache, or none
+// when c is the default, so the cache keeps its plain configuration.
+func reuseClockOpts(c Clock) []ReuseTokenSourceOpt {
+	if c == defaultClock {
+		return nil
+	}
+	return []ReuseTokenSourceOpt{WithReuseClock(c)}
+}
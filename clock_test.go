@@ -0,0 +1,198 @@
+package githubauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	// waiting receives a value every time After is called.
+	waiting chan time.Duration
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waiting: make(chan time.Duration, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	c.mu.Unlock()
+	c.waiting <- d
+	return ch
+}
+
+// Advance moves the clock forward by d and fires the waiters that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+func TestWithClock(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	ts, err := NewApplicationTokenSource(int64(1), privateKey, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(first.AccessToken, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if want := clock.Now().Add(-time.Minute); !claims.IssuedAt.Time.Equal(want) {
+		t.Errorf("iat = %v, want %v", claims.IssuedAt.Time, want)
+	}
+	if want := clock.Now().Add(9 * time.Minute); !first.Expiry.Equal(want) {
+		t.Errorf("Expiry = %v, want %v", first.Expiry, want)
+	}
+
+	// Just outside the skew window the cached JWT is served.
+	clock.Advance(9*time.Minute - DefaultExpirySkew - time.Second)
+	if tok, _ := ts.Token(); tok.AccessToken != first.AccessToken {
+		t.Error("Token() refreshed before the skew window")
+	}
+
+	clock.Advance(time.Second)
+	if tok, _ := ts.Token(); tok.AccessToken == first.AccessToken {
+		t.Error("Token() served the cached JWT inside the skew window")
+	}
+}
+
+func TestWithReuseClock(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	src := &countingSource{mkToken: func(call int) *oauth2.Token {
+		return &oauth2.Token{AccessToken: strconv.Itoa(call), Expiry: clock.Now().Add(time.Hour)}
+	}}
+	ts := ReuseTokenSourceWithSkew(nil, src, time.Minute, WithReuseClock(clock))
+
+	for _, step := range []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "1"},
+		{58 * time.Minute, "1"},
+		{time.Minute, "2"},
+	} {
+		clock.Advance(step.advance)
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != step.want {
+			t.Errorf("after %v: token = %q, want %q", step.advance, tok.AccessToken, step.want)
+		}
+	}
+}
+
+func TestWithInstallationClock(t *testing.T) {
+	clock := newFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch attempts.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			// X-RateLimit-Reset is interpreted against the injected clock.
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(clock.Now().Add(45*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(InstallationToken{Token: "ghs_test", ExpiresAt: clock.Now().Add(time.Hour)})
+		}
+	}))
+	defer server.Close()
+
+	ts := NewInstallationTokenSource(1, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL), WithInstallationClock(clock))
+
+	for _, wantDelay := range []time.Duration{30 * time.Second, 45 * time.Second} {
+		done := make(chan error, 1)
+		go func() {
+			_, err := ts.Token()
+			done <- err
+		}()
+
+		select {
+		case d := <-clock.waiting:
+			if d != wantDelay {
+				t.Errorf("retry delay = %v, want %v", d, wantDelay)
+			}
+		case err := <-done:
+			t.Fatalf("Token() returned %v before sleeping on the clock", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Token() did not sleep on the clock")
+		}
+		clock.Advance(wantDelay)
+		if err := <-done; err != nil {
+			t.Fatalf("Token() err = %v", err)
+		}
+
+		// The cache judges expiry by the fake clock too: an hour later the
+		// token must be refreshed.
+		clock.Advance(time.Hour)
+	}
+	if got := attempts.Load(); got != 4 {
+		t.Errorf("attempts = %d, want 4", got)
+	}
+}
+
+func Test_sleepCtx_Clock(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sleepCtx(ctx, clock, time.Hour) }()
+
+	<-clock.waiting
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("sleepCtx() err = %v, want context.Canceled", err)
+	}
+}
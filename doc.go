@@ -41,6 +41,13 @@
 // already-expired credential. The window is tunable with WithExpirySkew
 // (application JWTs) and WithInstallationExpirySkew (installation tokens).
 //
+// Tokens can also be shared between processes. WithTokenStore (for
+// ReuseTokenSourceWithSkew) and WithInstallationTokenStore make the cache
+// consult a TokenStore before minting, so cooperating replicas or CLI
+// invocations reuse one unexpired installation token and only one of them
+// mints the next. NewFileTokenStore coordinates through a shared directory
+// with advisory file locks; NewMemoryTokenStore shares within a process.
+//
 // # Signing with external key stores
 //
 // NewApplicationTokenSourceFromSigner accepts any RSA-backed [crypto.Signer]
@@ -49,6 +56,23 @@
 // memory. GitHub requires RS256; non-RSA signers are rejected at
 // construction time.
 //
+// # Startup validation
+//
+// Validate signs an App JWT, calls GET /app and optionally mints an
+// installation token, returning a ValidationReport with the App slug, owner,
+// permissions, subscribed events, clock offset and key fingerprint. Run it at
+// startup to fail readiness probes with a clear reason instead of on the
+// first real request.
+//
+// # Observability
+//
+// Hooks (WithApplicationHooks, WithInstallationHooks) report every mint,
+// failure and cache lookup. Metrics builds dependency-free counters on them,
+// exported through expvar and in the Prometheus text format; Health serves
+// a readiness endpoint; WithLogger adds structured logs of installation
+// token sources. OpenTelemetry spans and metrics live in the separate
+// github.com/jferrl/go-githubauth/otel module.
+//
 // # GitHub Enterprise
 //
 // WithEnterpriseURL targets GitHub Enterprise Server, normalizing the URL
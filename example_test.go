@@ -179,3 +179,26 @@ func ExampleReuseTokenSourceWithSkew() {
 	src := githubauth.ReuseTokenSourceWithSkew(nil, upstream, 30*time.Second)
 	_ = src
 }
+
+// Check the App credentials at startup and fail the readiness probe with a
+// clear reason instead of on the first real request.
+func ExampleValidate() {
+	privateKey := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	clientID := os.Getenv("GITHUB_APP_CLIENT_ID")
+	installationID, _ := strconv.ParseInt(os.Getenv("GITHUB_INSTALLATION_ID"), 10, 64)
+
+	appSource, err := githubauth.NewApplicationTokenSource(clientID, privateKey)
+	if err != nil {
+		fmt.Println("creating application token source:", err)
+		return
+	}
+
+	report, err := githubauth.Validate(context.Background(), appSource, installationID)
+	if err != nil {
+		fmt.Println("credentials are not usable:", err)
+		return
+	}
+
+	fmt.Printf("authenticated as %s (owner %s, key %s, clock offset %s)\n",
+		report.Slug, report.Owner, report.KeyFingerprint, report.ClockOffset)
+}
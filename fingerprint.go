@@ -0,0 +1,124 @@
+package githubauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// ErrKeyRejected is returned by CheckApplicationKey when GitHub does not
+// accept JWTs signed with the key for the given App. Callers can branch with
+// errors.Is.
+var ErrKeyRejected = errors.New("private key is not accepted by the GitHub App")
+
+// KeyFingerprint returns the SHA-256 fingerprint of a PEM-encoded RSA key in
+// the "SHA256:<base64>" form GitHub lists next to each private key on the App
+// settings page. Both private keys (PKCS#1 or PKCS#8, as downloaded from
+// GitHub) and public keys are accepted; a private key yields the fingerprint
+// of its public half.
+//
+// The value matches
+//
+//	openssl rsa -in key.pem -pubout -outform DER | openssl sha256 -binary | openssl base64
+//
+// See https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/managing-private-keys-for-github-apps#verifying-private-keys
+func KeyFingerprint(pemKey []byte) (string, error) {
+	if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pemKey); err == nil {
+		return publicKeyFingerprint(&priv.PublicKey)
+	}
+	pub, err := jwt.ParseRSAPublicKeyFromPEM(pemKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PEM key: %w", err)
+	}
+	return publicKeyFingerprint(pub)
+}
+
+// SignerFingerprint returns the GitHub-style SHA-256 fingerprint of the
+// public key behind signer, so a KMS, HSM or ssh-agent backed key can be
+// matched against the keys listed on the App settings page without exporting
+// it. See KeyFingerprint for the format.
+func SignerFingerprint(signer crypto.Signer) (string, error) {
+	if signer == nil {
+		return "", errors.New("signer is required")
+	}
+	return publicKeyFingerprint(signer.Public())
+}
+
+// ApplicationKeyFingerprint returns the GitHub-style SHA-256 fingerprint of
+// the key used by src, which must have been created by
+// NewApplicationTokenSource or NewApplicationTokenSourceFromSigner. It lets
+// operators tell which App key a running process has loaded.
+func ApplicationKeyFingerprint(src oauth2.TokenSource) (string, error) {
+	app, ok := applicationSourceOf(src)
+	if !ok {
+		return "", errors.New("token source was not created by NewApplicationTokenSource or NewApplicationTokenSourceFromSigner")
+	}
+	return SignerFingerprint(app.signer)
+}
+
+// CheckApplicationKey confirms that GitHub accepts JWTs signed by signer for
+// the App identified by id (an int64 App ID or string Client ID). It calls
+// GET /app and verifies that the App GitHub authenticated matches id. A key
+// GitHub rejects, or one that belongs to a different App, yields an error
+// wrapping ErrKeyRejected.
+//
+// opts select the API endpoint and transport exactly as for
+// NewInstallationTokenSource (WithEnterpriseURL, WithBaseURL, WithHTTPClient).
+func CheckApplicationKey[T Identifier](ctx context.Context, id T, signer crypto.Signer, opts ...InstallationTokenSourceOpt) error {
+	src, err := NewApplicationTokenSourceFromSigner(id, signer)
+	if err != nil {
+		return err
+	}
+
+	report, err := Validate(ctx, src, 0, opts...)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%w: %w", ErrKeyRejected, err)
+		}
+		return err
+	}
+
+	issuer, _ := resolveIssuer(id)
+	if issuer != report.ClientID && issuer != strconv.FormatInt(report.AppID, 10) {
+		return fmt.Errorf("%w: key authenticates as App %q (ID %d), not %q", ErrKeyRejected, report.Slug, report.AppID, issuer)
+	}
+	return nil
+}
+
+// publicKeyFingerprint returns the SHA-256 fingerprint GitHub displays for
+// an App private key: the base64-encoded SHA-256 digest of the DER-encoded
+// PKIX public key, prefixed with "SHA256:".
+func publicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// applicationSourceOf returns the applicationTokenSource behind src when src
+// was built by NewApplicationTokenSource or NewApplicationTokenSourceFromSigner,
+// looking through the caching wrappers installed by ReuseTokenSourceWithSkew.
+func applicationSourceOf(src oauth2.TokenSource) (*applicationTokenSource, bool) {
+	switch s := src.(type) {
+	case *applicationTokenSource:
+		return s, true
+	case *reuseTokenSourceWithSkew:
+		return applicationSourceOf(s.src)
+	case *reuseTokenSource:
+		return applicationSourceOf(s.src)
+	default:
+		return nil, false
+	}
+}
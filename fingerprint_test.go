@@ -0,0 +1,134 @@
+package githubauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+func TestKeyFingerprint(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Equivalent of: openssl rsa -pubout -outform DER | openssl sha256 -binary | openssl base64
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(der)
+	want := "SHA256:" + base64.StdEncoding.EncodeToString(sum[:])
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	tests := []struct {
+		name string
+		get  func() (string, error)
+	}{
+		{"private key PEM", func() (string, error) { return KeyFingerprint(privateKey) }},
+		{"public key PEM", func() (string, error) { return KeyFingerprint(publicPEM) }},
+		{"signer", func() (string, error) { return SignerFingerprint(rsaKey) }},
+		{"application source", func() (string, error) {
+			src, err := NewApplicationTokenSource(int64(1), privateKey)
+			if err != nil {
+				return "", err
+			}
+			return ApplicationKeyFingerprint(src)
+		}},
+		{"application source without skew", func() (string, error) {
+			src, err := NewApplicationTokenSourceFromSigner(int64(1), rsaKey, WithExpirySkew(0))
+			if err != nil {
+				return "", err
+			}
+			return ApplicationKeyFingerprint(src)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != want {
+				t.Errorf("fingerprint = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyFingerprint_Errors(t *testing.T) {
+	if _, err := KeyFingerprint([]byte("not a key")); err == nil {
+		t.Error("KeyFingerprint(garbage) err = nil, want error")
+	}
+	if _, err := SignerFingerprint(nil); err == nil {
+		t.Error("SignerFingerprint(nil) err = nil, want error")
+	}
+	if _, err := ApplicationKeyFingerprint(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "x"})); err == nil {
+		t.Error("ApplicationKeyFingerprint(static source) err = nil, want error")
+	}
+}
+
+func TestCheckApplicationKey(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server accepts only JWTs that verify against rsaKey, mimicking
+	// GitHub's lookup of the App's registered public keys.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.Header.Get("Authorization")[len("Bearer "):]
+		_, err := jwt.Parse(raw, func(*jwt.Token) (any, error) { return &rsaKey.PublicKey, nil },
+			jwt.WithValidMethods([]string{"RS256"}))
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(App{ID: 42, ClientID: "Iv1.abc", Slug: "octo-app"})
+	}))
+	defer server.Close()
+
+	otherPEM, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := jwt.ParseRSAPrivateKeyFromPEM(otherPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := CheckApplicationKey(ctx, int64(42), rsaKey, WithBaseURL(server.URL)); err != nil {
+		t.Errorf("matching App ID: err = %v", err)
+	}
+	if err := CheckApplicationKey(ctx, "Iv1.abc", rsaKey, WithBaseURL(server.URL)); err != nil {
+		t.Errorf("matching Client ID: err = %v", err)
+	}
+	if err := CheckApplicationKey(ctx, int64(7), rsaKey, WithBaseURL(server.URL)); !errors.Is(err, ErrKeyRejected) {
+		t.Errorf("different App: err = %v, want ErrKeyRejected", err)
+	}
+	if err := CheckApplicationKey(ctx, int64(42), otherKey, WithBaseURL(server.URL)); !errors.Is(err, ErrKeyRejected) {
+		t.Errorf("wrong key: err = %v, want ErrKeyRejected", err)
+	}
+}
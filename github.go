@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -31,6 +32,23 @@ const (
 // (HTTP 429 or 403 with rate-limit headers). Callers can branch with errors.Is.
 var ErrRateLimited = errors.New("github API rate limited")
 
+// APIError is returned when the GitHub API answers with a non-2xx status.
+// Callers can inspect it with errors.As to branch on the status code or to
+// quote the request ID when contacting GitHub support.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Body is the raw response body, usually a JSON document with a message.
+	Body string
+	// RequestID is the value of the X-GitHub-Request-Id response header.
+	RequestID string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API returned status %d: %s", e.StatusCode, e.Body)
+}
+
 // InstallationTokenOptions specifies options for creating an installation token.
 type InstallationTokenOptions struct {
 	// Repositories is a list of repository names that the token should have access to.
@@ -93,11 +111,34 @@ type Repository struct {
 	Name *string `json:"name,omitempty"`
 }
 
+// App represents the GitHub App authenticated by an App JWT, as returned by
+// GET /app.
+//
+// See https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#get-the-authenticated-app
+type App struct {
+	ID          int64             `json:"id"`
+	ClientID    string            `json:"client_id"`
+	Slug        string            `json:"slug"`
+	Name        string            `json:"name"`
+	Owner       *Account          `json:"owner,omitempty"`
+	Permissions map[string]string `json:"permissions,omitempty"`
+	Events      []string          `json:"events,omitempty"`
+}
+
+// Account represents the user or organization that owns a GitHub App.
+type Account struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
 // githubClient is a simple GitHub API client for creating installation tokens.
 type githubClient struct {
 	baseURL         *url.URL
 	httpClient      *http.Client
 	retryOnThrottle bool
+	logger          *slog.Logger
+	clock           Clock
 }
 
 // newGitHubClient creates a new GitHub API client.
@@ -108,6 +149,8 @@ func newGitHubClient(httpClient *http.Client) *githubClient {
 		baseURL:         baseURL,
 		httpClient:      httpClient,
 		retryOnThrottle: true,
+		logger:          discardLogger,
+		clock:           defaultClock,
 	}
 }
 
@@ -159,41 +202,87 @@ func (c *githubClient) withBaseURL(baseURL string) (*githubClient, error) {
 //
 // API documentation: https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#create-an-installation-access-token-for-an-app
 func (c *githubClient) createInstallationToken(ctx context.Context, installationID int64, opts *InstallationTokenOptions) (*InstallationToken, error) {
+	token, _, err := c.mintInstallationToken(ctx, installationID, opts)
+	return token, err
+}
+
+// mintStats describes the requests made by mintInstallationToken, for
+// refresh hooks.
+type mintStats struct {
+	// attempts is the number of POST requests made.
+	attempts int
+	// statusCode, requestID and rateLimitRemaining are taken from the last
+	// response; rateLimitRemaining is -1 when the header was absent.
+	statusCode         int
+	requestID          string
+	rateLimitRemaining int
+}
+
+// record copies the response metadata of an attempt into s.
+func (s *mintStats) record(resp *http.Response) {
+	s.statusCode = resp.StatusCode
+	s.requestID = resp.Header.Get("X-GitHub-Request-Id")
+	s.rateLimitRemaining = -1
+	if v, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		s.rateLimitRemaining = v
+	}
+}
+
+// mintInstallationToken implements createInstallationToken and additionally
+// reports the requests it made, for refresh hooks.
+func (c *githubClient) mintInstallationToken(ctx context.Context, installationID int64, opts *InstallationTokenOptions) (*InstallationToken, mintStats, error) {
+	stats := mintStats{rateLimitRemaining: -1}
+
 	endpoint := fmt.Sprintf("app/installations/%d/access_tokens", installationID)
 	u, err := c.baseURL.Parse(endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse endpoint URL: %w", err)
+		return nil, stats, fmt.Errorf("failed to parse endpoint URL: %w", err)
 	}
 
 	var bodyBytes []byte
 	if opts != nil {
 		bodyBytes, err = json.Marshal(opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, stats, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 	}
 
-	token, delay, err := c.doCreateInstallationToken(ctx, u.String(), bodyBytes)
+	stats.attempts = 1
+	token, delay, err := c.doCreateInstallationToken(ctx, u.String(), bodyBytes, &stats)
 	if err == nil {
-		return token, nil
+		return token, stats, nil
 	}
 	if !c.retryOnThrottle || !errors.Is(err, ErrRateLimited) {
-		return nil, err
+		return nil, stats, err
 	}
 
-	if sleepErr := sleepCtx(ctx, delay); sleepErr != nil {
-		return nil, sleepErr
+	attrs := []any{
+		slog.Int64(LogKeyInstallationID, installationID),
+		slog.Int(LogKeyAttempt, 1),
+		slog.Duration(LogKeyRetryDelay, delay),
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		attrs = append(attrs, slog.Int(LogKeyStatusCode, apiErr.StatusCode), slog.String(LogKeyRequestID, apiErr.RequestID))
 	}
+	c.logger.WarnContext(ctx, "installation token request throttled; sleeping before retry", attrs...)
 
-	token, _, err = c.doCreateInstallationToken(ctx, u.String(), bodyBytes)
-	return token, err
+	if sleepErr := sleepCtx(ctx, c.clock, delay); sleepErr != nil {
+		// Keep the throttled response as the cause, so the failure is
+		// classified as throttled rather than as the cancellation.
+		return nil, stats, fmt.Errorf("%w: %w", err, sleepErr)
+	}
+
+	stats.attempts = 2
+	token, _, err = c.doCreateInstallationToken(ctx, u.String(), bodyBytes, &stats)
+	return token, stats, err
 }
 
-// doCreateInstallationToken performs a single POST attempt. On a throttled
-// response it returns the desired retry delay in addition to the error so the
-// caller can decide whether to retry. A zero delay indicates the error is not
-// retryable.
-func (c *githubClient) doCreateInstallationToken(ctx context.Context, reqURL string, bodyBytes []byte) (*InstallationToken, time.Duration, error) {
+// doCreateInstallationToken performs a single POST attempt, recording the
+// response metadata in stats. On a throttled response it returns the desired
+// retry delay in addition to the error so the caller can decide whether to
+// retry. A zero delay indicates the error is not retryable.
+func (c *githubClient) doCreateInstallationToken(ctx context.Context, reqURL string, bodyBytes []byte, stats *mintStats) (*InstallationToken, time.Duration, error) {
 	var body io.Reader
 	if bodyBytes != nil {
 		body = bytes.NewReader(bodyBytes)
@@ -214,6 +303,7 @@ func (c *githubClient) doCreateInstallationToken(ctx context.Context, reqURL str
 	defer func() {
 		_ = resp.Body.Close()
 	}()
+	stats.record(resp)
 
 	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
 		var token InstallationToken
@@ -223,12 +313,99 @@ func (c *githubClient) doCreateInstallationToken(ctx context.Context, reqURL str
 		return &token, 0, nil
 	}
 
-	bodyResp, _ := io.ReadAll(resp.Body)
+	apiErr := newAPIError(resp)
 	if delay, ok := c.throttleDelay(resp); ok {
-		return nil, delay, fmt.Errorf("%w: GitHub API returned status %d: %s", ErrRateLimited, resp.StatusCode, string(bodyResp))
+		return nil, delay, fmt.Errorf("%w: %w", ErrRateLimited, apiErr)
+	}
+
+	return nil, 0, apiErr
+}
+
+// getApp fetches the App authenticated by the client's JWT. Alongside the App
+// it returns the server time taken from the Date response header (zero when
+// absent), which is also returned on error so callers can diagnose clock skew
+// behind a 401.
+//
+// API documentation: https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#get-the-authenticated-app
+func (c *githubClient) getApp(ctx context.Context) (*App, time.Time, error) {
+	u, err := c.baseURL.Parse("app")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse endpoint URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	serverTime, _ := http.ParseTime(resp.Header.Get("Date"))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, serverTime, newAPIError(resp)
+	}
+
+	var app App
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return nil, serverTime, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &app, serverTime, nil
+}
+
+// getInstallationID returns the ID of the installation at the endpoint path,
+// one of repos/{owner}/{repo}/installation, orgs/{org}/installation or
+// users/{user}/installation.
+func (c *githubClient) getInstallationID(ctx context.Context, path string) (int64, error) {
+	u, err := c.baseURL.Parse(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse endpoint URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return nil, 0, fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, string(bodyResp))
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newAPIError(resp)
+	}
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&installation); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return installation.ID, nil
+}
+
+// newAPIError builds an *APIError from a non-2xx response, consuming its body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RequestID:  resp.Header.Get("X-GitHub-Request-Id"),
+	}
 }
 
 // throttleDelay inspects a non-2xx response and reports the retry hint from
@@ -243,14 +420,14 @@ func (c *githubClient) throttleDelay(resp *http.Response) (time.Duration, bool)
 	}
 
 	if v := resp.Header.Get("Retry-After"); v != "" {
-		if d, ok := parseRetryAfter(v, time.Now()); ok {
+		if d, ok := parseRetryAfter(v, c.clock.Now()); ok {
 			return capDelay(d), true
 		}
 	}
 
 	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
 		if reset, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
-			return capDelay(time.Until(time.Unix(reset, 0))), true
+			return capDelay(time.Unix(reset, 0).Sub(c.clock.Now())), true
 		}
 	}
 
@@ -286,17 +463,16 @@ func capDelay(d time.Duration) time.Duration {
 	return d
 }
 
-// sleepCtx sleeps for d or until ctx is cancelled, whichever comes first.
-func sleepCtx(ctx context.Context, d time.Duration) error {
+// sleepCtx sleeps on clock for d or until ctx is cancelled, whichever comes
+// first.
+func sleepCtx(ctx context.Context, clock Clock, d time.Duration) error {
 	if d <= 0 {
 		return nil
 	}
-	t := time.NewTimer(d)
-	defer t.Stop()
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-t.C:
+	case <-clock.After(d):
 		return nil
 	}
 }
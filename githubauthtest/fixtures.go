@@ -0,0 +1,191 @@
+package githubauthtest
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"embed"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jferrl/go-githubauth"
+)
+
+// NumKeys is the number of fixture keys available to PrivateKey.
+const NumKeys = 3
+
+// keyFiles holds the fixture keys: 2048-bit RSA keys generated once and
+// committed, so tests neither pay for key generation nor depend on
+// randomness. They are public and must never be used outside tests.
+//
+//go:embed keys/*.pem
+var keyFiles embed.FS
+
+var parsedKeys [NumKeys]func() *rsa.PrivateKey
+
+func init() {
+	for i := range parsedKeys {
+		parsedKeys[i] = sync.OnceValue(func() *rsa.PrivateKey {
+			key, err := x509.ParsePKCS1PrivateKey(decodePEM(PrivateKeyPEM(i)))
+			if err != nil {
+				panic(fmt.Sprintf("githubauthtest: fixture key %d: %v", i, err))
+			}
+			return key
+		})
+	}
+}
+
+// PrivateKey returns fixture key i, 0 <= i < NumKeys. The same index always
+// returns the same key, parsed once and shared; callers must not modify it.
+func PrivateKey(i int) *rsa.PrivateKey {
+	checkIndex(i)
+	return parsedKeys[i]()
+}
+
+// PrivateKeyPEM returns fixture key i as a PKCS#1 "RSA PRIVATE KEY" PEM
+// block, the format GitHub issues App keys in and NewApplicationTokenSource
+// accepts.
+func PrivateKeyPEM(i int) []byte {
+	checkIndex(i)
+	b, err := keyFiles.ReadFile(fmt.Sprintf("keys/key%d.pem", i))
+	if err != nil {
+		panic(fmt.Sprintf("githubauthtest: fixture key %d: %v", i, err))
+	}
+	return b
+}
+
+// PublicKeyPEM returns the public half of fixture key i as a PKIX "PUBLIC
+// KEY" PEM block.
+func PublicKeyPEM(i int) []byte {
+	der, err := x509.MarshalPKIXPublicKey(&PrivateKey(i).PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("githubauthtest: fixture key %d: %v", i, err))
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func checkIndex(i int) {
+	if i < 0 || i >= NumKeys {
+		panic(fmt.Sprintf("githubauthtest: fixture key index %d out of range [0, %d)", i, NumKeys))
+	}
+}
+
+func decodePEM(b []byte) []byte {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		panic("githubauthtest: malformed fixture PEM")
+	}
+	return block.Bytes
+}
+
+// Signer is a crypto.Signer test double wrapping a real signer. It counts
+// calls and can be made to fail or to wait before signing, to exercise
+// NewApplicationTokenSourceFromSigner with a misbehaving KMS or HSM. It is
+// safe for concurrent use and may be reconfigured while in use.
+type Signer struct {
+	signer crypto.Signer
+
+	mu    sync.Mutex
+	err   error
+	delay time.Duration
+	calls int
+}
+
+// NewSigner returns a Signer delegating to signer, or to PrivateKey(0) when
+// signer is nil.
+func NewSigner(signer crypto.Signer) *Signer {
+	if signer == nil {
+		signer = PrivateKey(0)
+	}
+	return &Signer{signer: signer}
+}
+
+// FailWith makes subsequent Sign calls return err; nil restores signing. It
+// returns s for chaining.
+func (s *Signer) FailWith(err error) *Signer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	return s
+}
+
+// Delay makes subsequent Sign calls wait d before signing or failing. It
+// returns s for chaining.
+func (s *Signer) Delay(d time.Duration) *Signer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+	return s
+}
+
+// Calls returns the number of Sign calls made so far, including failed ones.
+func (s *Signer) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Public implements crypto.Signer.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+// Sign implements crypto.Signer.
+func (s *Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mu.Lock()
+	s.calls++
+	err, delay := s.err, s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.signer.Sign(rand, digest, opts)
+}
+
+// Claims are the claims of an App JWT.
+type Claims struct {
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// AssertAppJWT checks that token is an App JWT GitHub would accept from the
+// App identified by issuer (App ID or Client ID) with public key key: RS256
+// signed by key, with matching iss, iat and exp claims at most 10 minutes
+// apart. It fails tb on any mismatch and returns the claims. Times are not
+// compared with the current time, so tokens stamped by a fake Clock pass.
+func AssertAppJWT(tb testing.TB, token string, key *rsa.PublicKey, issuer string) Claims {
+	tb.Helper()
+
+	claims, err := githubauth.DecodeApplicationJWT(token)
+	if err != nil {
+		tb.Fatalf("App JWT is not valid: %v", err)
+	}
+	// Verified as of its iat, so only the signature and claims are checked.
+	at := fixedClock(claims.IssuedAt)
+	if _, err := githubauth.VerifyApplicationJWT(token, key, githubauth.WithVerifyClock(at)); err != nil {
+		tb.Fatalf("App JWT is not valid: %v", err)
+	}
+	if claims.Issuer != issuer {
+		tb.Errorf("App JWT iss = %q, want %q", claims.Issuer, issuer)
+	}
+	return Claims{
+		Issuer:    claims.Issuer,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}
+}
+
+// fixedClock is a githubauth.Clock that is always at one instant.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time                       { return time.Time(c) }
+func (fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,82 @@
+package githubauthtest
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jferrl/go-githubauth"
+)
+
+func TestFixtureKeys(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := range NumKeys {
+		key := PrivateKey(i)
+		if key != PrivateKey(i) {
+			t.Errorf("PrivateKey(%d) is not cached", i)
+		}
+		if key.N.BitLen() != 2048 {
+			t.Errorf("PrivateKey(%d) has %d bits, want 2048", i, key.N.BitLen())
+		}
+
+		fp, err := githubauth.KeyFingerprint(PrivateKeyPEM(i))
+		if err != nil {
+			t.Fatalf("KeyFingerprint(PrivateKeyPEM(%d)) err = %v", i, err)
+		}
+		pubFP, err := githubauth.KeyFingerprint(PublicKeyPEM(i))
+		if err != nil || pubFP != fp {
+			t.Errorf("public key fingerprint = %q (%v), want %q", pubFP, err, fp)
+		}
+		if seen[fp] {
+			t.Errorf("fixture key %d duplicates another key", i)
+		}
+		seen[fp] = true
+
+		block, _ := pem.Decode(PublicKeyPEM(i))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil || !key.PublicKey.Equal(pub.(*rsa.PublicKey)) {
+			t.Errorf("PublicKeyPEM(%d) does not match PrivateKey(%d)", i, i)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("PrivateKey(NumKeys) did not panic")
+		}
+	}()
+	PrivateKey(NumKeys)
+}
+
+func TestSigner(t *testing.T) {
+	errKMS := errors.New("kms unavailable")
+	signer := NewSigner(nil).FailWith(errKMS)
+
+	ts, err := githubauth.NewApplicationTokenSourceFromSigner("Iv1.abc", signer, githubauth.WithExpirySkew(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); !errors.Is(err, errKMS) {
+		t.Fatalf("Token() err = %v, want %v", err, errKMS)
+	}
+
+	signer.FailWith(nil).Delay(20 * time.Millisecond)
+	start := time.Now()
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Token() took %v, want at least the 20ms signer delay", elapsed)
+	}
+	if got := signer.Calls(); got != 2 {
+		t.Errorf("Calls() = %d, want 2", got)
+	}
+
+	claims := AssertAppJWT(t, tok.AccessToken, &PrivateKey(0).PublicKey, "Iv1.abc")
+	if got := claims.ExpiresAt.Sub(claims.IssuedAt); got != githubauth.DefaultApplicationTokenExpiration {
+		t.Errorf("JWT lifetime = %v, want %v", got, githubauth.DefaultApplicationTokenExpiration)
+	}
+}
@@ -0,0 +1,456 @@
+// Package githubauthtest provides an in-process fake of the GitHub API
+// endpoints used by github.com/jferrl/go-githubauth, for testing code that
+// authenticates as a GitHub App without reaching GitHub.
+//
+// The fake validates App JWTs against registered public keys the way GitHub
+// does, mints fake installation tokens, injects failures and records every
+// request:
+//
+//	srv := githubauthtest.NewServer()
+//	defer srv.Close()
+//	srv.AddApp(githubauthtest.App{ID: 1, Key: &privateKey.PublicKey})
+//	srv.AddInstallation(githubauthtest.Installation{ID: 42})
+//
+//	appSrc, _ := githubauth.NewApplicationTokenSourceFromSigner(int64(1), privateKey)
+//	ts := githubauth.NewInstallationTokenSource(42, appSrc, githubauth.WithBaseURL(srv.URL))
+package githubauthtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jferrl/go-githubauth"
+	"github.com/jferrl/go-githubauth/internal/tokenformat"
+)
+
+const (
+	// DefaultTokenTTL is the lifetime of minted installation tokens, the
+	// same as GitHub's.
+	DefaultTokenTTL = time.Hour
+)
+
+// App is a GitHub App known to the Server.
+type App struct {
+	// ID is the App ID. JWTs may use it or ClientID as issuer.
+	ID int64
+	// ClientID is the Client ID; optional.
+	ClientID string
+	// Slug and Name are returned by GET /app.
+	Slug string
+	Name string
+	// Key is the public key JWTs of the App are verified with.
+	Key *rsa.PublicKey
+}
+
+// Installation is an installation known to the Server.
+type Installation struct {
+	// ID is the installation ID.
+	ID int64
+	// AppID restricts the installation to the App with this ID. Zero lets
+	// any registered App mint tokens for it.
+	AppID int64
+	// Permissions are granted to tokens minted without requested
+	// permissions.
+	Permissions *githubauth.InstallationPermissions
+}
+
+// Fault is a canned error response, see Server.InjectFault.
+type Fault struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Header is added to the response.
+	Header http.Header
+	// Body is the response body. When empty a GitHub style JSON message is
+	// written.
+	Body string
+}
+
+// RateLimited returns a 429 fault asking the client to retry after d.
+func RateLimited(retryAfter time.Duration) Fault {
+	return Fault{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {strconv.Itoa(int(retryAfter / time.Second))}},
+	}
+}
+
+// SecondaryRateLimited returns a 403 fault with an exhausted rate limit that
+// resets at reset.
+func SecondaryRateLimited(reset time.Time) Fault {
+	return Fault{
+		StatusCode: http.StatusForbidden,
+		Header: http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+		},
+	}
+}
+
+// Status returns a fault answering with status code; 401, 403 and 5xx are
+// the usual candidates.
+func Status(code int) Fault {
+	return Fault{StatusCode: code}
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	// Issuer is the iss claim of the App JWT, when one could be decoded.
+	Issuer string
+	// InstallationID is set for installation token requests.
+	InstallationID int64
+	// Options is the decoded body of installation token requests, nil when
+	// the body was empty.
+	Options *githubauth.InstallationTokenOptions
+	// StatusCode is the status the Server answered with.
+	StatusCode int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTokenTTL sets the lifetime of minted installation tokens. Defaults to
+// DefaultTokenTTL.
+func WithTokenTTL(d time.Duration) Option {
+	return func(s *Server) {
+		s.tokenTTL = d
+	}
+}
+
+// WithClock sets the Clock JWTs are validated and tokens stamped with, so the
+// Server agrees with token sources created with githubauth.WithClock or
+// githubauth.WithInstallationClock.
+func WithClock(c githubauth.Clock) Option {
+	return func(s *Server) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// Server is a fake GitHub API. It serves:
+//
+//   - POST /app/installations/{id}/access_tokens
+//   - GET /app
+//
+// Point token sources at it with githubauth.WithBaseURL(srv.URL). Server is
+// safe for concurrent use; Apps, installations and faults may be added while
+// it is serving.
+type Server struct {
+	// URL is the base URL of the Server, for githubauth.WithBaseURL.
+	URL string
+
+	srv      *httptest.Server
+	tokenTTL time.Duration
+	clock    githubauth.Clock // nil for the system clock
+
+	mu            sync.Mutex
+	apps          []App
+	installations map[int64]Installation
+	faults        []Fault
+	requests      []Request
+}
+
+// NewServer starts a Server. Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		tokenTTL:      DefaultTokenTTL,
+		installations: make(map[int64]Installation),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /app/installations/{id}/access_tokens", s.handleAccessTokens)
+	mux.HandleFunc("GET /app", s.handleApp)
+	s.srv = httptest.NewServer(s.record(mux))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the Server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns an HTTP client for the Server.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// AddApp registers app so JWTs it signs are accepted.
+func (s *Server) AddApp(app App) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps = append(s.apps, app)
+}
+
+// AddInstallation registers inst so tokens can be minted for it.
+func (s *Server) AddInstallation(inst Installation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.installations[inst.ID] = inst
+}
+
+// InjectFault makes the next request, to any endpoint, fail with f instead
+// of being served. Faults queue up: injecting two fails the next two
+// requests, in order.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, f)
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets recorded requests and pending faults. Registered Apps and
+// installations are kept.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.faults = nil
+}
+
+// recordKey carries the in-flight Request between record and the handlers.
+type recordKey struct{}
+
+// record captures every request, serves pending faults and stamps GitHub's
+// request ID header.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+
+		s.mu.Lock()
+		idx := len(s.requests)
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		var fault *Fault
+		if len(s.faults) > 0 {
+			fault = &s.faults[0]
+			s.faults = s.faults[1:]
+		}
+		s.mu.Unlock()
+
+		r = r.WithContext(context.WithValue(r.Context(), recordKey{}, idx))
+		rec := &statusRecorder{ResponseWriter: w}
+		rec.Header().Set("X-GitHub-Request-Id", fmt.Sprintf("FAKE:%d", idx+1))
+		if fault != nil {
+			writeFault(rec, *fault)
+		} else {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(rec, r)
+		}
+
+		s.update(r, func(req *Request) { req.StatusCode = rec.status })
+	})
+}
+
+// update edits the Request recorded for r.
+func (s *Server) update(r *http.Request, fn func(*Request)) {
+	idx, ok := r.Context().Value(recordKey{}).(int)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if idx < len(s.requests) { // Reset may have run meanwhile
+		fn(&s.requests[idx])
+	}
+}
+
+func (s *Server) handleAccessTokens(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	s.update(r, func(req *Request) { req.InstallationID = id })
+
+	app, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	inst, found := s.installations[id]
+	s.mu.Unlock()
+	if !found || (inst.AppID != 0 && inst.AppID != app.ID) {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	var opts *githubauth.InstallationTokenOptions
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		opts = &githubauth.InstallationTokenOptions{}
+		if err := json.Unmarshal(body, opts); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		s.update(r, func(req *Request) { req.Options = opts })
+	}
+
+	token := githubauth.InstallationToken{
+		Token:       "ghs_" + newTokenBody(),
+		ExpiresAt:   s.now().Add(s.tokenTTL).UTC().Truncate(time.Second),
+		Permissions: inst.Permissions,
+	}
+	if opts != nil {
+		if opts.Permissions != nil {
+			token.Permissions = opts.Permissions
+		}
+		for _, name := range opts.Repositories {
+			token.Repositories = append(token.Repositories, githubauth.Repository{Name: githubauth.Ptr(name)})
+		}
+		for _, repoID := range opts.RepositoryIDs {
+			token.Repositories = append(token.Repositories, githubauth.Repository{ID: githubauth.Ptr(repoID)})
+		}
+	}
+	writeJSON(w, http.StatusCreated, token)
+}
+
+func (s *Server) handleApp(w http.ResponseWriter, r *http.Request) {
+	app, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, githubauth.App{
+		ID:       app.ID,
+		ClientID: app.ClientID,
+		Slug:     app.Slug,
+		Name:     app.Name,
+	})
+}
+
+// authenticate validates the App JWT of r, writing a 401 when it is not
+// acceptable.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (App, bool) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		writeMessage(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return App{}, false
+	}
+
+	// The issuer selects the key to verify with, so it is read before the
+	// signature is checked.
+	claims, err := githubauth.DecodeApplicationJWT(raw)
+	if err != nil {
+		writeMessage(w, http.StatusUnauthorized, "A JSON web token could not be decoded: "+err.Error())
+		return App{}, false
+	}
+	s.update(r, func(req *Request) { req.Issuer = claims.Issuer })
+	app, ok := s.appByIssuer(claims.Issuer)
+	if !ok {
+		writeMessage(w, http.StatusUnauthorized, fmt.Sprintf("A JSON web token could not be decoded: no App registered for issuer %q", claims.Issuer))
+		return App{}, false
+	}
+	if _, err := githubauth.VerifyApplicationJWT(raw, app.Key, githubauth.WithVerifyClock(s.clock)); err != nil {
+		writeMessage(w, http.StatusUnauthorized, "A JSON web token could not be decoded: "+err.Error())
+		return App{}, false
+	}
+	return app, true
+}
+
+func (s *Server) appByIssuer(iss string) (App, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, app := range s.apps {
+		if iss != "" && (iss == strconv.FormatInt(app.ID, 10) || iss == app.ClientID) {
+			return app, true
+		}
+	}
+	return App{}, false
+}
+
+func writeFault(w http.ResponseWriter, f Fault) {
+	for k, vs := range f.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	if f.Body != "" {
+		w.WriteHeader(f.StatusCode)
+		_, _ = io.WriteString(w, f.Body)
+		return
+	}
+	writeMessage(w, f.StatusCode, http.StatusText(f.StatusCode))
+}
+
+func writeMessage(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{
+		"message":           msg,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newTokenBody returns the random part of a minted token followed by the
+// checksum GitHub embeds in its tokens, so minted tokens pass
+// githubauth.ParseToken.
+func newTokenBody() string {
+	b := make([]byte, tokenformat.EntropyLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(errors.New("githubauthtest: " + err.Error()))
+	}
+	for i := range b {
+		b[i] = tokenformat.Alphabet[int(b[i])%len(tokenformat.Alphabet)]
+	}
+	return string(b) + tokenformat.Checksum(string(b))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// now returns the current time of the Server's Clock.
+func (s *Server) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
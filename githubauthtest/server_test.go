@@ -0,0 +1,179 @@
+package githubauthtest
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jferrl/go-githubauth"
+	"golang.org/x/oauth2"
+)
+
+func newAppSource(t *testing.T, key *rsa.PrivateKey) oauth2.TokenSource {
+	t.Helper()
+	src, err := githubauth.NewApplicationTokenSourceFromSigner(int64(1), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestServer_MintsInstallationTokens(t *testing.T) {
+	key := PrivateKey(0)
+	srv := NewServer(WithTokenTTL(30 * time.Minute))
+	defer srv.Close()
+	srv.AddApp(App{ID: 1, Key: &key.PublicKey})
+	srv.AddInstallation(Installation{ID: 42, AppID: 1})
+
+	ts := githubauth.NewInstallationTokenSource(42, newAppSource(t, key), githubauth.WithBaseURL(srv.URL))
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind, err := githubauth.ParseToken(tok.AccessToken); kind != githubauth.TokenKindInstallation || err != nil {
+		t.Errorf("ParseToken(%q) = %v, %v, want a well-formed ghs_ token", tok.AccessToken, kind, err)
+	}
+	if ttl := time.Until(tok.Expiry); ttl < 29*time.Minute || ttl > 30*time.Minute {
+		t.Errorf("token expires in %v, want ~30m", ttl)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	got := reqs[0]
+	if got.Method != http.MethodPost || got.Path != "/app/installations/42/access_tokens" ||
+		got.InstallationID != 42 || got.Issuer != "1" || got.Options != nil || got.StatusCode != http.StatusCreated {
+		t.Errorf("request = %+v", got)
+	}
+}
+
+func TestServer_TokenOptions(t *testing.T) {
+	key := PrivateKey(0)
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddApp(App{ID: 1, Key: &key.PublicKey})
+	srv.AddInstallation(Installation{ID: 42})
+
+	client := githubauth.NewInstallationTokenSource(42, newAppSource(t, key), githubauth.WithBaseURL(srv.URL),
+		githubauth.WithInstallationTokenOptions(&githubauth.InstallationTokenOptions{
+			Repositories: []string{"octo-repo"},
+			Permissions:  &githubauth.InstallationPermissions{Contents: githubauth.Ptr("read")},
+		}))
+	if _, err := client.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := srv.Requests()[0].Options
+	if opts == nil || len(opts.Repositories) != 1 || opts.Repositories[0] != "octo-repo" || *opts.Permissions.Contents != "read" {
+		t.Errorf("recorded options = %+v", opts)
+	}
+}
+
+func TestServer_RejectsJWTs(t *testing.T) {
+	key := PrivateKey(0)
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddApp(App{ID: 1, Key: &key.PublicKey})
+	srv.AddInstallation(Installation{ID: 42, AppID: 1})
+	srv.AddInstallation(Installation{ID: 43, AppID: 2})
+
+	tooLong := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    "1",
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	signed, err := tooLong.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		installationID int64
+		src            oauth2.TokenSource
+		wantStatus     int
+	}{
+		{"wrong key", 42, newAppSource(t, PrivateKey(1)), http.StatusUnauthorized},
+		{"lifetime over 10 minutes", 42, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: signed}), http.StatusUnauthorized},
+		{"not a JWT", 42, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ghp_nope"}), http.StatusUnauthorized},
+		{"unknown installation", 7, newAppSource(t, key), http.StatusNotFound},
+		{"installation of another App", 43, newAppSource(t, key), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := githubauth.NewInstallationTokenSource(tt.installationID, tt.src, githubauth.WithBaseURL(srv.URL))
+			_, err := ts.Token()
+			var apiErr *githubauth.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+				t.Fatalf("Token() err = %v, want status %d", err, tt.wantStatus)
+			}
+			if !strings.HasPrefix(apiErr.RequestID, "FAKE:") {
+				t.Errorf("RequestID = %q, want FAKE:n", apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestServer_InjectFault(t *testing.T) {
+	key := PrivateKey(0)
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddApp(App{ID: 1, Key: &key.PublicKey})
+	srv.AddInstallation(Installation{ID: 42})
+
+	srv.InjectFault(RateLimited(0))
+	srv.InjectFault(Status(http.StatusBadGateway))
+
+	newSource := func() oauth2.TokenSource {
+		return githubauth.NewInstallationTokenSource(42, newAppSource(t, key), githubauth.WithBaseURL(srv.URL))
+	}
+
+	// The 429 is retried, the retry hits the 502.
+	_, err := newSource().Token()
+	var apiErr *githubauth.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Token() err = %v, want 502", err)
+	}
+	// Faults are consumed: the next request succeeds.
+	if _, err := newSource().Token(); err != nil {
+		t.Fatalf("Token() after faults err = %v", err)
+	}
+
+	var statuses []int
+	for _, r := range srv.Requests() {
+		statuses = append(statuses, r.StatusCode)
+	}
+	if want := []int{429, 502, 201}; len(statuses) != 3 || statuses[0] != want[0] || statuses[1] != want[1] || statuses[2] != want[2] {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+
+	srv.Reset()
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("requests after Reset = %d, want 0", n)
+	}
+}
+
+func TestServer_GetApp(t *testing.T) {
+	key := PrivateKey(0)
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddApp(App{ID: 1, ClientID: "Iv1.abc", Slug: "octo-app", Name: "Octo App", Key: &key.PublicKey})
+
+	src, err := githubauth.NewApplicationTokenSourceFromSigner("Iv1.abc", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := githubauth.Validate(context.Background(), src, 0, githubauth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.AppID != 1 || report.Slug != "octo-app" || report.ClientID != "Iv1.abc" {
+		t.Errorf("report = %+v", report)
+	}
+}
@@ -0,0 +1,234 @@
+package githubauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Health states reported by HealthStatus.Status.
+const (
+	// HealthOK reports a valid installation token and no failed refresh
+	// since it was minted.
+	HealthOK = "ok"
+	// HealthDegraded reports a valid installation token while the last
+	// refresh or App JWT signature failed: requests still succeed, but will
+	// start failing once the token expires unless the failure clears.
+	HealthDegraded = "degraded"
+	// HealthUnavailable reports that no valid installation token is
+	// available.
+	HealthUnavailable = "unavailable"
+)
+
+// defaultHealthProbeTimeout bounds how long Health.Handler waits for its
+// probe, well below the timeouts of common readiness checks.
+const defaultHealthProbeTimeout = 2 * time.Second
+
+// Values of HealthStatus.Signing.
+const (
+	signingUnknown = "unknown"
+	signingOK      = "ok"
+	signingFailed  = "failed"
+)
+
+// Health tracks whether a GitHub App can still authenticate and serves the
+// result as a readiness endpoint. It is fed by hooks:
+//
+//	health := githubauth.NewHealth()
+//	appSrc, err := githubauth.NewApplicationTokenSource(appID, privateKey,
+//		githubauth.WithApplicationHooks(health.ApplicationHooks()))
+//	installationSrc := githubauth.NewInstallationTokenSource(installationID, appSrc,
+//		githubauth.WithInstallationHooks(health.InstallationHooks()))
+//	http.Handle("/healthz/github", health.Handler(installationSrc))
+//
+// Health is safe for concurrent use.
+type Health struct {
+	mu sync.Mutex
+
+	signing      string
+	lastMint     time.Time
+	expiry       time.Time
+	lastErr      error
+	lastErrClass ErrorClass
+	signErr      error
+
+	// probing is closed when the probe in flight returns; nil when none is.
+	probing      chan struct{}
+	probeTimeout time.Duration
+}
+
+// HealthStatus is the state reported by Health.
+type HealthStatus struct {
+	// Status is HealthOK, HealthDegraded or HealthUnavailable.
+	Status string `json:"status"`
+	// Signing is "ok" or "failed" after the App JWT was last signed, and
+	// "unknown" before the first signature.
+	Signing string `json:"signing"`
+	// LastSuccessfulMint is when an installation token was last minted; zero
+	// if none was.
+	LastSuccessfulMint time.Time `json:"last_successful_mint,omitzero"`
+	// ExpiresIn is the remaining lifetime of the newest installation token
+	// seen; zero once it has expired.
+	ExpiresIn time.Duration `json:"-"`
+	// ExpiresInSeconds is ExpiresIn in whole seconds, for the JSON body.
+	ExpiresInSeconds int64 `json:"expires_in_seconds"`
+	// LastError and LastErrorClass describe the most recent refresh failure
+	// since the last successful mint, if any.
+	LastError      string `json:"last_error,omitempty"`
+	LastErrorClass string `json:"last_error_class,omitempty"`
+}
+
+// NewHealth returns a Health that has observed no token activity yet.
+func NewHealth() *Health {
+	return &Health{signing: signingUnknown, probeTimeout: defaultHealthProbeTimeout}
+}
+
+// InstallationHooks returns hooks recording installation token mints and
+// failures. Register them with WithInstallationHooks.
+func (h *Health) InstallationHooks() Hooks {
+	return Hooks{
+		OnRefresh: func(ev RefreshEvent) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.lastMint = time.Now()
+			h.observeExpiry(ev.Expiry)
+			h.lastErr = nil
+		},
+		OnError: func(ev RefreshEvent) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.lastErr = ev.Err
+			h.lastErrClass = ev.ErrorClass
+		},
+	}
+}
+
+// ApplicationHooks returns hooks recording whether App JWTs can be signed.
+// Register them with WithApplicationHooks.
+func (h *Health) ApplicationHooks() Hooks {
+	return Hooks{
+		OnRefresh: func(RefreshEvent) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.signing = signingOK
+			h.signErr = nil
+		},
+		OnError: func(ev RefreshEvent) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.signing = signingFailed
+			h.signErr = ev.Err
+		},
+	}
+}
+
+// observeExpiry records the expiry of a token served or minted. The caller
+// holds h.mu.
+func (h *Health) observeExpiry(expiry time.Time) {
+	if expiry.After(h.expiry) {
+		h.expiry = expiry
+	}
+}
+
+// Status returns the current state.
+func (h *Health) Status() HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := HealthStatus{
+		Status:             HealthOK,
+		Signing:            h.signing,
+		LastSuccessfulMint: h.lastMint,
+	}
+	if remaining := time.Until(h.expiry); remaining > 0 {
+		s.ExpiresIn = remaining
+		s.ExpiresInSeconds = int64(remaining / time.Second)
+	}
+
+	err, class := h.lastErr, h.lastErrClass
+	if err == nil && h.signErr != nil {
+		err, class = h.signErr, ClassifyError(h.signErr)
+	}
+	if err != nil {
+		s.LastError = err.Error()
+		s.LastErrorClass = class.String()
+		s.Status = HealthDegraded
+	}
+	if s.ExpiresIn == 0 {
+		s.Status = HealthUnavailable
+	}
+	return s
+}
+
+// Handler returns an http.Handler reporting Status as JSON, with status 200
+// while a valid installation token is available (HealthOK or
+// HealthDegraded) and 503 otherwise.
+//
+// When probe is not nil, the handler calls probe.Token() first, so the
+// check is accurate before the first request was made and a token that
+// expired is refreshed. Pass the installation token source the hooks are
+// registered on; cached tokens make the probe cheap. A mint can take long,
+// e.g. while waiting out a rate limit, so the handler waits for the probe
+// for at most two seconds or until the request is canceled, and otherwise
+// answers from the state observed so far, reporting HealthOK as
+// HealthDegraded. The probe keeps running and later requests wait for it
+// rather than starting another. With a nil probe the handler only reports
+// what the hooks have observed, and is unavailable until the first
+// installation token is minted.
+func (h *Health) Handler(probe oauth2.TokenSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed := true
+		if probe != nil {
+			timer := time.NewTimer(h.probeTimeout)
+			select {
+			case <-h.probe(probe):
+			case <-r.Context().Done():
+				probed = false
+			case <-timer.C:
+				probed = false
+			}
+			timer.Stop()
+		}
+
+		s := h.Status()
+		if !probed && s.Status == HealthOK {
+			s.Status = HealthDegraded
+			s.LastError = "installation token probe still running"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if s.Status == HealthUnavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(s)
+	})
+}
+
+// probe calls ts.Token() in the background, unless a probe is already in
+// flight, and returns a channel closed when it returns. Failures are
+// recorded by the hooks.
+func (h *Health) probe(ts oauth2.TokenSource) <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.probing != nil {
+		return h.probing
+	}
+
+	done := make(chan struct{})
+	h.probing = done
+	go func() {
+		tok, err := ts.Token()
+
+		h.mu.Lock()
+		if err == nil {
+			h.observeExpiry(tok.Expiry)
+		}
+		h.probing = nil
+		h.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,151 @@
+package githubauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func serveHealth(t *testing.T, h http.Handler) (int, HealthStatus) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/github", nil))
+	var s HealthStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("body %q is not a HealthStatus: %v", rec.Body.String(), err)
+	}
+	return rec.Code, s
+}
+
+func TestHealth_Handler(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusCreated, writeToken: true},
+		{status: http.StatusBadGateway, body: "bad gateway"},
+	}, &attempts))
+	defer server.Close()
+
+	health := NewHealth()
+	ts := NewInstallationTokenSource(1, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(health.InstallationHooks()),
+		WithInstallationExpirySkew(2*time.Hour), // every call refreshes
+	)
+	handler := health.Handler(ts)
+
+	code, s := serveHealth(t, handler)
+	if code != http.StatusOK || s.Status != HealthOK {
+		t.Fatalf("first probe = %d %+v, want 200 ok", code, s)
+	}
+	if s.LastSuccessfulMint.IsZero() || s.ExpiresInSeconds <= 0 || s.ExpiresInSeconds > 3600 {
+		t.Errorf("first probe = %+v, want a recent mint expiring within the hour", s)
+	}
+
+	// The refresh fails while the token minted before is still valid.
+	code, s = serveHealth(t, handler)
+	if code != http.StatusOK || s.Status != HealthDegraded || s.LastErrorClass != "other" || s.LastError == "" {
+		t.Errorf("second probe = %d %+v, want 200 degraded", code, s)
+	}
+}
+
+func TestHealth_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	health := NewHealth()
+	ts := NewInstallationTokenSource(1, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(health.InstallationHooks()),
+	)
+
+	code, s := serveHealth(t, health.Handler(ts))
+	if code != http.StatusServiceUnavailable || s.Status != HealthUnavailable || s.LastErrorClass != "auth" {
+		t.Errorf("probe = %d %+v, want 503 unavailable with auth error", code, s)
+	}
+	if s.Signing != "unknown" || !s.LastSuccessfulMint.IsZero() {
+		t.Errorf("probe = %+v, want unknown signing and no mint", s)
+	}
+
+	// Without a probe nothing has been observed yet.
+	if code, _ := serveHealth(t, NewHealth().Handler(nil)); code != http.StatusServiceUnavailable {
+		t.Errorf("passive handler status = %d, want 503", code)
+	}
+}
+
+func TestHealth_ApplicationHooks(t *testing.T) {
+	health := NewHealth()
+	hooks := health.ApplicationHooks()
+	health.InstallationHooks().OnRefresh(RefreshEvent{Expiry: time.Now().Add(time.Hour)})
+
+	hooks.OnError(RefreshEvent{Err: errors.New("kms unavailable"), ErrorClass: ErrorClassOther})
+	if s := health.Status(); s.Signing != "failed" || s.Status != HealthDegraded || s.LastError != "kms unavailable" {
+		t.Errorf("Status() after signer error = %+v, want failed signing, degraded", s)
+	}
+
+	hooks.OnRefresh(RefreshEvent{})
+	if s := health.Status(); s.Signing != "ok" || s.Status != HealthOK || s.LastError != "" {
+		t.Errorf("Status() after signature = %+v, want ok", s)
+	}
+}
+
+// slowSource serves its first token at once and blocks later calls until
+// release is closed.
+type slowSource struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowSource) Token() (*oauth2.Token, error) {
+	if s.calls.Add(1) > 1 {
+		<-s.release
+	}
+	return &oauth2.Token{AccessToken: "ghs_slow", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestHealth_SlowProbe(t *testing.T) {
+	src := &slowSource{release: make(chan struct{})}
+	health := NewHealth()
+	health.probeTimeout = 20 * time.Millisecond
+	handler := health.Handler(src)
+
+	if code, s := serveHealth(t, handler); code != http.StatusOK || s.Status != HealthOK {
+		t.Fatalf("first probe = %d %+v, want 200 ok", code, s)
+	}
+
+	// The second probe blocks, e.g. on a rate limit: the handler answers
+	// from the token seen before, without waiting for the mint.
+	for range 3 {
+		code, s := serveHealth(t, handler)
+		if code != http.StatusOK || s.Status != HealthDegraded || s.ExpiresInSeconds <= 0 {
+			t.Errorf("blocked probe = %d %+v, want 200 degraded", code, s)
+		}
+	}
+
+	// A canceled request is answered at once.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	rec := httptest.NewRecorder()
+	health.probeTimeout = time.Hour
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/github", nil).WithContext(ctx))
+	if rec.Code != http.StatusOK {
+		t.Errorf("canceled probe status = %d, want 200", rec.Code)
+	}
+
+	if n := src.calls.Load(); n != 2 {
+		t.Errorf("Token() calls = %d, want 2: one probe in flight at a time", n)
+	}
+
+	close(src.release)
+	if code, s := serveHealth(t, handler); code != http.StatusOK || s.Status != HealthOK {
+		t.Errorf("probe after release = %d %+v, want 200 ok", code, s)
+	}
+}
@@ -0,0 +1,206 @@
+package githubauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrorClass categorizes a token refresh failure for alerting.
+type ErrorClass int
+
+const (
+	// ErrorClassNone is reported for successful refreshes.
+	ErrorClassNone ErrorClass = iota
+	// ErrorClassThrottled reports a refresh rejected by GitHub's rate
+	// limiting (the error wraps ErrRateLimited).
+	ErrorClassThrottled
+	// ErrorClassAuth reports credentials GitHub rejected (HTTP 401 or 403),
+	// such as a wrong key or a suspended installation.
+	ErrorClassAuth
+	// ErrorClassNetwork reports a failure to reach GitHub: DNS, connection
+	// and TLS errors, and timeouts.
+	ErrorClassNetwork
+	// ErrorClassOther reports any other failure, including signer errors and
+	// unexpected API responses.
+	ErrorClassOther
+)
+
+// String returns the lowercase name of the class.
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassNone:
+		return "none"
+	case ErrorClassThrottled:
+		return "throttled"
+	case ErrorClassAuth:
+		return "auth"
+	case ErrorClassNetwork:
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// ClassifyError reports the ErrorClass of an error returned by a token
+// source of this package.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+	if errors.Is(err, ErrRateLimited) {
+		return ErrorClassThrottled
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden {
+			return ErrorClassAuth
+		}
+		return ErrorClassOther
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassNetwork
+	}
+	return ErrorClassOther
+}
+
+// RefreshEvent describes a single token refresh, successful or not.
+type RefreshEvent struct {
+	// InstallationID is the installation the token was minted for, or zero
+	// for App JWTs and tokens from sources outside this package.
+	InstallationID int64
+	// Expiry is the expiry of the new token; zero on failure.
+	Expiry time.Time
+	// Duration is how long the refresh took, including throttle retries.
+	Duration time.Duration
+	// Attempts is the number of requests made to GitHub (1, or 2 after a
+	// throttle retry). It is 1 for App JWT signing.
+	Attempts int
+	// Err is the refresh error, or nil on success.
+	Err error
+	// ErrorClass categorizes Err; ErrorClassNone on success.
+	ErrorClass ErrorClass
+	// StatusCode, RequestID and RateLimitRemaining are taken from the last
+	// GitHub response of an installation token refresh. They are zero, empty
+	// and -1 respectively when no response was received, for App JWTs, and
+	// for tokens from sources outside this package. RateLimitRemaining is
+	// also -1 when the response carried no X-RateLimit-Remaining header.
+	StatusCode         int
+	RequestID          string
+	RateLimitRemaining int
+}
+
+// CacheEvent describes a token cache lookup.
+type CacheEvent struct {
+	// InstallationID is the installation the cache holds tokens for, or zero
+	// for App JWTs and caches created with ReuseTokenSourceWithSkew.
+	InstallationID int64
+	// Hit reports whether the token was served without minting, either from
+	// memory or from a TokenStore.
+	Hit bool
+}
+
+// Hooks are callbacks fired from the token refresh path. They run
+// synchronously on the goroutine calling Token(), while the token cache is
+// locked, so they must be fast and must not call Token() on the same source.
+// Nil callbacks are skipped.
+type Hooks struct {
+	// OnRefresh is called after a token was minted successfully.
+	OnRefresh func(RefreshEvent)
+	// OnError is called after a refresh failed.
+	OnError func(RefreshEvent)
+	// OnCacheLookup is called for every Token() call on the cache in front of
+	// the source, reporting whether the cached token was served.
+	OnCacheLookup func(CacheEvent)
+}
+
+// fire dispatches ev to OnRefresh or OnError.
+func (h Hooks) fire(ev RefreshEvent) {
+	if ev.Err != nil {
+		if h.OnError != nil {
+			h.OnError(ev)
+		}
+		return
+	}
+	if h.OnRefresh != nil {
+		h.OnRefresh(ev)
+	}
+}
+
+// lookup dispatches ev to OnCacheLookup.
+func (h Hooks) lookup(ev CacheEvent) {
+	if h.OnCacheLookup != nil {
+		h.OnCacheLookup(ev)
+	}
+}
+
+// joinHooks returns Hooks calling the callbacks of a, then those of b.
+func joinHooks(a, b Hooks) Hooks {
+	return Hooks{
+		OnRefresh:     joinCallbacks(a.OnRefresh, b.OnRefresh),
+		OnError:       joinCallbacks(a.OnError, b.OnError),
+		OnCacheLookup: joinCallbacks(a.OnCacheLookup, b.OnCacheLookup),
+	}
+}
+
+func joinCallbacks[E any](a, b func(E)) func(E) {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(ev E) {
+		a(ev)
+		b(ev)
+	}
+}
+
+// WithApplicationHooks registers hooks fired every time
+// NewApplicationTokenSource or NewApplicationTokenSourceFromSigner signs a
+// new App JWT, and on every lookup of the JWT cache. The option may be
+// repeated; all registered hooks are called in order.
+func WithApplicationHooks(h Hooks) ApplicationTokenOpt {
+	return func(a *applicationTokenSource) {
+		a.hooks = joinHooks(a.hooks, h)
+	}
+}
+
+// WithInstallationHooks registers hooks fired every time
+// NewInstallationTokenSource mints a new installation token, with the
+// installation ID, the number of attempts made and the metadata of GitHub's
+// response, and on every lookup of the token cache. The option may be
+// repeated; all registered hooks are called in order.
+func WithInstallationHooks(h Hooks) InstallationTokenSourceOpt {
+	return func(i *installationTokenSource) {
+		i.hooks = joinHooks(i.hooks, h)
+	}
+}
+
+// WithReuseHooks registers hooks fired every time the cache returned by
+// ReuseTokenSourceWithSkew refreshes from its underlying source, and on
+// every lookup. Tokens taken from a TokenStore count as cache hits and do
+// not fire OnRefresh or OnError. The option may be repeated; all registered
+// hooks are called in order.
+func WithReuseHooks(h Hooks) ReuseTokenSourceOpt {
+	return func(r *reuseTokenSourceWithSkew) {
+		r.hooks = joinHooks(r.hooks, h)
+	}
+}
+
+// withCacheHooks reports the cache lookups of the wrapper to the
+// OnCacheLookup hook of the source it wraps, tagged with installationID.
+// Refresh events are fired by the wrapped source itself.
+func withCacheHooks(installationID int64, h Hooks) ReuseTokenSourceOpt {
+	return func(r *reuseTokenSourceWithSkew) {
+		r.installationID = installationID
+		r.hooks = joinHooks(r.hooks, Hooks{OnCacheLookup: h.OnCacheLookup})
+	}
+}
@@ -0,0 +1,256 @@
+package githubauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ErrorClassNone},
+		{"rate limited", fmt.Errorf("%w: %w", ErrRateLimited, &APIError{StatusCode: 429}), ErrorClassThrottled},
+		{"unauthorized", &APIError{StatusCode: 401}, ErrorClassAuth},
+		{"forbidden", fmt.Errorf("wrapped: %w", &APIError{StatusCode: 403}), ErrorClassAuth},
+		{"server error", &APIError{StatusCode: 502}, ErrorClassOther},
+		{"transport", fmt.Errorf("failed to execute request: %w", &url.Error{Op: "Post", URL: "https://api.github.com", Err: errors.New("connection refused")}), ErrorClassNetwork},
+		{"deadline", context.DeadlineExceeded, ErrorClassNetwork},
+		{"signer", errors.New("kms unavailable"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingHooks collects the events fired through its Hooks.
+type recordingHooks struct {
+	refreshes []RefreshEvent
+	errors    []RefreshEvent
+	lookups   []CacheEvent
+}
+
+func (r *recordingHooks) hooks() Hooks {
+	return Hooks{
+		OnRefresh: func(ev RefreshEvent) { r.refreshes = append(r.refreshes, ev) },
+		OnError:   func(ev RefreshEvent) { r.errors = append(r.errors, ev) },
+		OnCacheLookup: func(ev CacheEvent) {
+			r.lookups = append(r.lookups, ev)
+		},
+	}
+}
+
+func TestWithInstallationHooks(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}},
+		{status: http.StatusCreated, writeToken: true, headers: map[string]string{
+			"X-GitHub-Request-Id":   "ABCD:1234",
+			"X-RateLimit-Remaining": "4999",
+		}},
+		{status: http.StatusUnauthorized, body: `{"message":"Bad credentials"}`},
+	}, &attempts))
+	defer server.Close()
+
+	rec := &recordingHooks{}
+	ts := NewInstallationTokenSource(42, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(rec.hooks()),
+		WithInstallationExpirySkew(2*time.Hour), // every call refreshes
+	)
+
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("first Token() err = %v", err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("second Token() err = nil, want 401")
+	}
+
+	if len(rec.refreshes) != 1 {
+		t.Fatalf("OnRefresh calls = %d, want 1", len(rec.refreshes))
+	}
+	ok := rec.refreshes[0]
+	if ok.InstallationID != 42 || ok.Attempts != 2 || ok.Expiry.IsZero() || ok.Duration <= 0 || ok.ErrorClass != ErrorClassNone {
+		t.Errorf("refresh event = %+v", ok)
+	}
+	if ok.StatusCode != http.StatusCreated || ok.RequestID != "ABCD:1234" || ok.RateLimitRemaining != 4999 {
+		t.Errorf("refresh event response = %d %q %d, want 201 \"ABCD:1234\" 4999", ok.StatusCode, ok.RequestID, ok.RateLimitRemaining)
+	}
+
+	if len(rec.errors) != 1 {
+		t.Fatalf("OnError calls = %d, want 1", len(rec.errors))
+	}
+	failed := rec.errors[0]
+	if failed.ErrorClass != ErrorClassAuth || failed.Attempts != 1 || failed.Err == nil {
+		t.Errorf("error event = %+v, want auth class after 1 attempt", failed)
+	}
+	if failed.StatusCode != http.StatusUnauthorized || failed.RateLimitRemaining != -1 {
+		t.Errorf("error event response = %d %d, want 401 -1", failed.StatusCode, failed.RateLimitRemaining)
+	}
+}
+
+func TestWithInstallationHooks_NetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	rec := &recordingHooks{}
+	ts := NewInstallationTokenSource(1, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL), WithInstallationHooks(rec.hooks()))
+
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("Token() err = nil, want connection error")
+	}
+	if len(rec.errors) != 1 || rec.errors[0].ErrorClass != ErrorClassNetwork {
+		t.Errorf("error events = %+v, want one network error", rec.errors)
+	}
+}
+
+func TestWithInstallationHooks_ThrottleSleepCanceled(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "30"}},
+	}, &attempts))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := newFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	go func() {
+		<-clock.waiting
+		cancel()
+	}()
+
+	rec := &recordingHooks{}
+	ts := NewInstallationTokenSource(42, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithContext(ctx),
+		WithInstallationClock(clock),
+		WithInstallationHooks(rec.hooks()),
+	)
+
+	_, err := ts.Token()
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Token() err = %v, want ErrRateLimited and context.Canceled", err)
+	}
+	if len(rec.errors) != 1 {
+		t.Fatalf("OnError calls = %d, want 1", len(rec.errors))
+	}
+	if ev := rec.errors[0]; ev.ErrorClass != ErrorClassThrottled || ev.Attempts != 1 || ev.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("error event = %+v, want throttled class after 1 attempt", ev)
+	}
+}
+
+func TestWithApplicationHooks(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recordingHooks{}
+	ts, err := NewApplicationTokenSource(int64(1), privateKey, WithApplicationHooks(rec.hooks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the first call signs; the rest are cache hits.
+	if len(rec.refreshes) != 1 || len(rec.errors) != 0 {
+		t.Fatalf("events = %d refreshes, %d errors, want 1 and 0", len(rec.refreshes), len(rec.errors))
+	}
+	if ev := rec.refreshes[0]; ev.InstallationID != 0 || ev.Attempts != 1 || ev.Expiry.IsZero() {
+		t.Errorf("refresh event = %+v", ev)
+	}
+}
+
+func TestWithReuseHooks(t *testing.T) {
+	sentinel := errors.New("upstream unavailable")
+	src := newShortLivedSource(time.Hour)
+	rec := &recordingHooks{}
+
+	ts := ReuseTokenSourceWithSkew(nil, src, 0, WithReuseHooks(rec.hooks()))
+	for range 2 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rec.refreshes) != 1 {
+		t.Errorf("OnRefresh calls = %d, want 1", len(rec.refreshes))
+	}
+
+	failing := ReuseTokenSourceWithSkew(nil, &countingSource{err: sentinel, mkToken: func(int) *oauth2.Token { return nil }},
+		DefaultExpirySkew, WithReuseHooks(rec.hooks()))
+	if _, err := failing.Token(); !errors.Is(err, sentinel) {
+		t.Fatalf("Token() err = %v, want %v", err, sentinel)
+	}
+	if len(rec.errors) != 1 || !errors.Is(rec.errors[0].Err, sentinel) || rec.errors[0].ErrorClass != ErrorClassOther {
+		t.Errorf("error events = %+v", rec.errors)
+	}
+}
+
+func TestHooks_CacheLookup(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusCreated, writeToken: true},
+	}, &attempts))
+	defer server.Close()
+
+	first, second := &recordingHooks{}, &recordingHooks{}
+	ts := NewInstallationTokenSource(7, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(first.hooks()),
+		WithInstallationHooks(second.hooks()),
+	)
+	for range 3 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []CacheEvent{{7, false}, {7, true}, {7, true}}
+	for _, rec := range []*recordingHooks{first, second} {
+		if fmt.Sprint(rec.lookups) != fmt.Sprint(want) {
+			t.Errorf("lookups = %v, want %v", rec.lookups, want)
+		}
+		// The cache in front of the source must not fire OnRefresh again.
+		if len(rec.refreshes) != 1 {
+			t.Errorf("OnRefresh calls = %d, want 1", len(rec.refreshes))
+		}
+	}
+}
+
+func TestHooks_CacheLookupStoreHit(t *testing.T) {
+	store := NewMemoryTokenStore()
+	key := TokenKey{App: "1", InstallationID: 3}
+	if err := store.Put(context.Background(), key, &oauth2.Token{AccessToken: "shared", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recordingHooks{}
+	src := &countingSource{mkToken: func(int) *oauth2.Token { return &oauth2.Token{AccessToken: "minted"} }}
+	ts := ReuseTokenSourceWithSkew(nil, src, DefaultExpirySkew, WithTokenStore(store, key), WithReuseHooks(rec.hooks()))
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.lookups) != 1 || !rec.lookups[0].Hit || len(rec.refreshes) != 0 {
+		t.Errorf("lookups = %v, refreshes = %d, want one hit and no refresh", rec.lookups, len(rec.refreshes))
+	}
+}
@@ -0,0 +1,190 @@
+package githubauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// installationLookupTTL bounds how long a looked up installation ID is reused
+// when no webhook invalidates it, e.g. after a repository transfer.
+const installationLookupTTL = time.Hour
+
+// InstallationCache hands out installation token sources for the
+// installations of one GitHub App. The source for an installation is created
+// with NewInstallationTokenSource on first use and shared afterwards, so
+// every caller asking for the same installation reuses one cached token
+// instead of minting its own.
+//
+// It also caches which installation serves a repository, organization or
+// user, see RepositoryInstallationID.
+//
+// Sources are kept until Invalidate drops them; one entry per installation
+// the App serves. InstallationCache is safe for concurrent use.
+type InstallationCache struct {
+	src  oauth2.TokenSource
+	opts []InstallationTokenSourceOpt
+
+	// base is an installation source built once from opts. Its client serves
+	// the installation lookups and its store and key locate stored tokens
+	// on Invalidate.
+	base *installationTokenSource
+
+	mu      sync.Mutex
+	sources map[int64]*reuseTokenSourceWithSkew
+	lookups map[string]installationLookup
+}
+
+// installationLookup is a cached installation ID lookup.
+type installationLookup struct {
+	id      int64
+	expires time.Time
+}
+
+// NewInstallationCache returns an empty InstallationCache minting tokens
+// with the GitHub App JWT token source src. opts are applied to every
+// installation token source, e.g. WithBaseURL, WithInstallationTokenStore or
+// WithInstallationHooks.
+func NewInstallationCache(src oauth2.TokenSource, opts ...InstallationTokenSourceOpt) *InstallationCache {
+	return &InstallationCache{
+		src:     src,
+		opts:    opts,
+		base:    newInstallationTokenSource(0, src, opts...),
+		sources: make(map[int64]*reuseTokenSourceWithSkew),
+		lookups: make(map[string]installationLookup),
+	}
+}
+
+// TokenSource returns the token source for installation id.
+func (c *InstallationCache) TokenSource(id int64) oauth2.TokenSource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ts, ok := c.sources[id]; ok {
+		return ts
+	}
+	// Always the skew-aware cache, so Invalidate can drop its token.
+	i := newInstallationTokenSource(id, c.src, c.opts...)
+	ts := newReuseTokenSourceWithSkew(nil, i, i.skew, i.reuseOpts()...)
+	c.sources[id] = ts
+	return ts
+}
+
+// Client returns an *http.Client authenticated as installation id. As with
+// oauth2.NewClient, ctx only supplies the base client (oauth2.HTTPClient);
+// it does not bound the client's requests.
+func (c *InstallationCache) Client(ctx context.Context, id int64) *http.Client {
+	return oauth2.NewClient(ctx, c.TokenSource(id))
+}
+
+// RepositoryInstallationID returns the ID of the installation of the App on
+// the repository owner/repo. The ID is looked up with the App JWT and cached
+// until Invalidate drops the installation, or for an hour. A repository the
+// App is not installed on yields an *APIError with StatusCode 404, which is
+// not cached.
+func (c *InstallationCache) RepositoryInstallationID(ctx context.Context, owner, repo string) (int64, error) {
+	return c.installationID(ctx, "repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo)+"/installation")
+}
+
+// OrganizationInstallationID returns the ID of the installation of the App on
+// the organization org, cached like RepositoryInstallationID.
+func (c *InstallationCache) OrganizationInstallationID(ctx context.Context, org string) (int64, error) {
+	return c.installationID(ctx, "orgs/"+url.PathEscape(org)+"/installation")
+}
+
+// UserInstallationID returns the ID of the installation of the App on the
+// personal account user, cached like RepositoryInstallationID.
+func (c *InstallationCache) UserInstallationID(ctx context.Context, user string) (int64, error) {
+	return c.installationID(ctx, "users/"+url.PathEscape(user)+"/installation")
+}
+
+// installationID returns the installation ID found at the endpoint path,
+// from the cache when possible. Concurrent misses may each query GitHub.
+func (c *InstallationCache) installationID(ctx context.Context, path string) (int64, error) {
+	if c.base.configErr != nil {
+		return 0, c.base.configErr
+	}
+
+	now := c.base.clock.Now()
+	c.mu.Lock()
+	l, ok := c.lookups[path]
+	c.mu.Unlock()
+	if ok && now.Before(l.expires) {
+		return l.id, nil
+	}
+
+	id, err := c.base.client.getInstallationID(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.lookups[path] = installationLookup{id: id, expires: now.Add(installationLookupTTL)}
+	c.mu.Unlock()
+	return id, nil
+}
+
+// Invalidate drops the cached token of installation id, so the next Token
+// call mints a new one, evicts its source from the cache and forgets the
+// repositories, organizations and users looked up to it. Call it when the
+// installation is deleted or suspended, or its repositories or permissions
+// change; webhook.InvalidateThen and webhook.InvalidationHandler do so from
+// installation webhooks.
+//
+// With WithInstallationTokenStore the token shared through the store is
+// deleted as well, also when this cache never used the installation, so
+// other processes stop reusing it. The delete waits for the store lock, so a
+// token another holder is minting at the time is deleted once published
+// rather than surviving the invalidation.
+func (c *InstallationCache) Invalidate(ctx context.Context, id int64) error {
+	c.mu.Lock()
+	ts := c.sources[id]
+	delete(c.sources, id)
+	for path, l := range c.lookups {
+		if l.id == id {
+			delete(c.lookups, path)
+		}
+	}
+	c.mu.Unlock()
+
+	// Callers may still hold the evicted source; it must not serve or
+	// publish the old token either. A refresh in flight is invalidated
+	// before the store entry is deleted, so it does not publish its token,
+	// and again after, in case the old entry was reloaded meanwhile.
+	if ts != nil {
+		ts.invalidate()
+	}
+	var err error
+	if c.base.store != nil {
+		err = c.deleteStored(ctx, id)
+	}
+	if ts != nil {
+		ts.invalidate()
+	}
+	return err
+}
+
+// deleteStored deletes the stored token of installation id under the store
+// lock. Without the lock the token is still deleted and the lock error is
+// reported.
+func (c *InstallationCache) deleteStored(ctx context.Context, id int64) error {
+	key := c.base.storeKey()
+	key.InstallationID = id
+
+	var errs []error
+	if unlock, err := c.base.store.Lock(ctx, key, defaultTokenStoreLease); err != nil {
+		errs = append(errs, fmt.Errorf("failed to lock stored token: %w", err))
+	} else {
+		defer unlock()
+	}
+	if err := c.base.store.Delete(ctx, key); err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete stored token: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,314 @@
+package githubauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInstallationCache(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mints atomic.Int32
+	var seen string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/app/installations/") {
+			n := mints.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(InstallationToken{
+				Token:     fmt.Sprintf("ghs_%s_%d", strings.Split(r.URL.Path, "/")[3], n),
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+			return
+		}
+		seen = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	cache := NewInstallationCache(appSrc, WithBaseURL(server.URL))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := cache.TokenSource(7).Token(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if cache.TokenSource(7) != cache.TokenSource(7) {
+		t.Error("TokenSource(7) returned different sources")
+	}
+	if n := mints.Load(); n != 1 {
+		t.Errorf("mints = %d, want 1 shared token", n)
+	}
+
+	tok, err := cache.TokenSource(8).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "ghs_8_2" {
+		t.Errorf("AccessToken = %q, want ghs_8_2", tok.AccessToken)
+	}
+
+	resp, err := cache.Client(t.Context(), 8).Get(server.URL + "/repos/o/r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if seen != "Bearer ghs_8_2" {
+		t.Errorf("Authorization = %q, want the cached installation token", seen)
+	}
+}
+
+func TestInstallationCache_Invalidate(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mints atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := mints.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(InstallationToken{
+			Token:     fmt.Sprintf("ghs_%d", n),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	ctx := t.Context()
+	store := NewMemoryTokenStore()
+	cache := NewInstallationCache(appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store))
+
+	held := cache.TokenSource(7)
+	if tok, err := held.Token(); err != nil || tok.AccessToken != "ghs_1" {
+		t.Fatalf("Token() = %v, %v", tok, err)
+	}
+	if err := cache.Invalidate(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.TokenSource(7) == held {
+		t.Error("Invalidate did not evict the source")
+	}
+	if _, err := store.Get(ctx, held.(*reuseTokenSourceWithSkew).key); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("store.Get() after Invalidate error = %v, want ErrTokenNotFound", err)
+	}
+	// A source obtained before Invalidate mints a new token too.
+	if tok, err := held.Token(); err != nil || tok.AccessToken != "ghs_2" {
+		t.Errorf("held Token() = %v, %v, want ghs_2", tok, err)
+	}
+
+	// Installations this cache never used are removed from the store.
+	other := NewInstallationTokenSource(8, appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store))
+	if _, err := other.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Invalidate(ctx, 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, other.(*reuseTokenSourceWithSkew).key); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("store.Get(8) after Invalidate error = %v, want ErrTokenNotFound", err)
+	}
+}
+
+func TestInstallationCache_InvalidateWithoutOptions(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mints atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := mints.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(InstallationToken{
+			Token:     fmt.Sprintf("ghs_%d", n),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	// No skew and no store: NewInstallationTokenSource would return the plain
+	// oauth2 cache here, which cannot be invalidated.
+	cache := NewInstallationCache(appSrc, WithBaseURL(server.URL), WithInstallationExpirySkew(0))
+
+	held := cache.TokenSource(7)
+	if tok, err := held.Token(); err != nil || tok.AccessToken != "ghs_1" {
+		t.Fatalf("Token() = %v, %v", tok, err)
+	}
+	if err := cache.Invalidate(t.Context(), 7); err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := held.Token(); err != nil || tok.AccessToken != "ghs_2" {
+		t.Errorf("held Token() = %v, %v, want ghs_2", tok, err)
+	}
+}
+
+func TestInstallationCache_InstallationID(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+			t.Errorf("Authorization = %q, want the App JWT", r.Header.Get("Authorization"))
+		}
+		ids := map[string]int64{
+			"/repos/octo/hello/installation": 7,
+			"/orgs/octo/installation":        7,
+			"/users/mona/installation":       8,
+		}
+		id, ok := ids[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int64{"id": id})
+	}))
+	defer server.Close()
+
+	ctx := t.Context()
+	cache := NewInstallationCache(appSrc, WithBaseURL(server.URL))
+
+	tests := []struct {
+		name   string
+		lookup func() (int64, error)
+		want   int64
+	}{
+		{"repository", func() (int64, error) { return cache.RepositoryInstallationID(ctx, "octo", "hello") }, 7},
+		{"organization", func() (int64, error) { return cache.OrganizationInstallationID(ctx, "octo") }, 7},
+		{"user", func() (int64, error) { return cache.UserInstallationID(ctx, "mona") }, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 2 {
+				id, err := tt.lookup()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id != tt.want {
+					t.Errorf("installation ID = %d, want %d", id, tt.want)
+				}
+			}
+		})
+	}
+	if n := lookups.Load(); n != 3 {
+		t.Errorf("lookups = %d, want 3 cached", n)
+	}
+
+	_, err = cache.RepositoryInstallationID(ctx, "octo", "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("RepositoryInstallationID() error = %v, want a 404 *APIError", err)
+	}
+
+	// Invalidate forgets the lookups of installation 7 only.
+	lookups.Store(0)
+	if err := cache.Invalidate(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if _, err := tt.lookup(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lookups.Load(); n != 2 {
+		t.Errorf("lookups after Invalidate(7) = %d, want 2", n)
+	}
+}
+
+func TestInstallationCache_InvalidateDuringMint(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mints atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := mints.Add(1)
+		if n == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(InstallationToken{
+			Token:     fmt.Sprintf("ghs_%d", n),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	ctx := t.Context()
+	store := NewMemoryTokenStore()
+	cache := NewInstallationCache(appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store))
+	held := cache.TokenSource(7)
+	key := held.(*reuseTokenSourceWithSkew).key
+
+	minted := make(chan error, 1)
+	go func() {
+		_, err := held.Token()
+		minted <- err
+	}()
+	<-started
+
+	// The permissions change while the token is being minted.
+	invalidated := make(chan error, 1)
+	go func() { invalidated <- cache.Invalidate(ctx, 7) }()
+	select {
+	case err := <-invalidated:
+		t.Fatalf("Invalidate() = %v returned while the mint held the store lock", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-minted; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-invalidated; err != nil {
+		t.Fatal(err)
+	}
+
+	if tok, err := store.Get(ctx, key); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("store.Get() = %v, %v, want the token minted across Invalidate not to be shared", tok, err)
+	}
+	if tok, err := held.Token(); err != nil || tok.AccessToken != "ghs_2" {
+		t.Errorf("held Token() = %v, %v, want a token minted after Invalidate", tok, err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package atomicfile
+
+// syncDir is a no-op where directories cannot be opened for syncing, such
+// as on Windows, which persists renames with the file metadata.
+func syncDir(string) error {
+	return nil
+}
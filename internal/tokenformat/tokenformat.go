@@ -0,0 +1,31 @@
+// Package tokenformat describes the format of GitHub's checksummed tokens,
+// shared by the token parser and the fake GitHub API of githubauthtest.
+//
+// See https://github.blog/engineering/platform-security/behind-githubs-new-authentication-token-formats/
+package tokenformat
+
+import "hash/crc32"
+
+const (
+	// Alphabet is the digit order GitHub uses to encode token checksums. The
+	// random part of tokens is drawn from the same characters.
+	Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// Checksummed tokens are a 4-character prefix followed by EntropyLength
+	// random base62 characters and the base62 CRC32 of those, zero-padded to
+	// ChecksumLength.
+	EntropyLength  = 30
+	ChecksumLength = 6
+)
+
+// Checksum returns the base62 CRC32 of entropy, zero-padded to
+// ChecksumLength.
+func Checksum(entropy string) string {
+	var b [ChecksumLength]byte
+	n := crc32.ChecksumIEEE([]byte(entropy))
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = Alphabet[n%uint32(len(Alphabet))]
+		n /= uint32(len(Alphabet))
+	}
+	return string(b[:])
+}
@@ -0,0 +1,33 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package githubauth
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// tryLockFile attempts to take an exclusive flock on path without blocking.
+// It returns a nil release func when the lock is held by someone else. The
+// kernel drops the lock if the process dies, so lease is not needed here.
+func tryLockFile(path string, _ time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,49 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package githubauth
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+)
+
+// tryLockFile attempts to create path exclusively. It returns a nil release
+// func when the lock file already exists. Without flock the operating system
+// cannot release the lock of a dead holder, so a lock file older than lease
+// is considered abandoned and removed.
+func tryLockFile(path string, lease time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > lease {
+			removeStaleLock(path, lease)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+
+	return func() { _ = os.Remove(path) }, nil
+}
+
+// removeStaleLock removes the lock file at path if it is older than lease.
+// Removing by name after a stat would race with another waiter that removed
+// the stale file and took the lock meanwhile, so the file is first moved
+// aside, which only one waiter can do, and checked again. A fresh lock moved
+// by mistake is linked back when no newer one took its place; where hard
+// links are unsupported it is lost and two holders may briefly overlap,
+// which costs an extra mint.
+func removeStaleLock(path string, lease time.Duration) {
+	aside := path + ".stale." + strconv.Itoa(os.Getpid()) + "." + strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	if info, err := os.Stat(aside); err == nil && time.Since(info.ModTime()) <= lease {
+		_ = os.Link(aside, path)
+	}
+	_ = os.Remove(aside)
+}
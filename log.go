@@ -0,0 +1,189 @@
+package githubauth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Attribute keys used in log records emitted through WithLogger. They are
+// part of the package's stable API so log pipelines can filter on them.
+const (
+	LogKeyInstallationID = "github.installation_id"
+	LogKeyAttempt        = "github.attempt"
+	LogKeyStatusCode     = "github.status_code"
+	LogKeyRequestID      = "github.request_id"
+	LogKeyRetryDelay     = "github.retry_delay"
+	LogKeyExpiry         = "github.token_expiry"
+	LogKeyDuration       = "github.duration"
+	LogKeyErrorClass     = "github.error_class"
+	LogKeyError          = "error"
+)
+
+// redactedMarker replaces secret material in logged and formatted values.
+const redactedMarker = "[REDACTED]"
+
+// discardLogger is used when no logger is configured.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// WithLogger logs installation token activity to logger: successful mints at
+// Debug, throttled responses with the sleep before the retry and token store
+// problems at Warn, and failed mints at Error. Records carry the LogKey*
+// attributes. Token values are never logged.
+//
+// Only installation token sources log. App JWT signing and the other token
+// sources of this package emit no log records; observe them through hooks
+// (WithApplicationHooks) instead.
+func WithLogger(logger *slog.Logger) InstallationTokenSourceOpt {
+	return func(i *installationTokenSource) {
+		if logger == nil {
+			logger = discardLogger
+		}
+		i.logger = logger
+		i.client.logger = logger
+	}
+}
+
+// redactSecret hides a secret while keeping a recognizable GitHub token
+// prefix (such as "ghs_") so redacted values remain useful when debugging.
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	if strings.HasPrefix(s, "github_pat_") {
+		return "github_pat_" + redactedMarker
+	}
+	if len(s) > 4 && s[3] == '_' && strings.HasPrefix(s, "gh") {
+		return s[:4] + redactedMarker
+	}
+	return redactedMarker
+}
+
+// LogValue implements slog.LogValuer so an InstallationToken logged through
+// log/slog never exposes the token value.
+func (t InstallationToken) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("token", redactSecret(t.Token)),
+		slog.Time("expires_at", t.ExpiresAt),
+		slog.Int("repositories", len(t.Repositories)),
+	)
+}
+
+// Format implements fmt.Formatter so printing an InstallationToken with any
+// verb (including %v, %+v and %#v) never exposes the token value.
+func (t InstallationToken) Format(f fmt.State, verb rune) {
+	type plain InstallationToken // drops the Format method
+	p := plain(t)
+	p.Token = redactSecret(p.Token)
+	if verb == 'v' && f.Flag('#') {
+		// Go syntax names the type; report the real one, not plain.
+		s := fmt.Sprintf("%#v", p)
+		_, _ = io.WriteString(f, strings.Replace(s, "githubauth.plain", "githubauth.InstallationToken", 1))
+		return
+	}
+	_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), p)
+}
+
+// RedactedToken wraps an *oauth2.Token so it can be logged or printed
+// without exposing the access or refresh token. oauth2.Token is defined
+// outside this package and cannot redact itself; wrap it with Redact
+// wherever a token is logged, or install RedactAttr on the slog handler to
+// catch tokens logged by mistake.
+type RedactedToken struct {
+	token *oauth2.Token
+}
+
+// Redact wraps t for safe logging and printing.
+func Redact(t *oauth2.Token) RedactedToken {
+	return RedactedToken{token: t}
+}
+
+// LogValue implements slog.LogValuer.
+func (r RedactedToken) LogValue() slog.Value {
+	if r.token == nil {
+		return slog.AnyValue(nil)
+	}
+	attrs := []slog.Attr{
+		slog.String("access_token", redactSecret(r.token.AccessToken)),
+		slog.String("token_type", r.token.TokenType),
+	}
+	if r.token.RefreshToken != "" {
+		attrs = append(attrs, slog.String("refresh_token", redactSecret(r.token.RefreshToken)))
+	}
+	if !r.token.Expiry.IsZero() {
+		attrs = append(attrs, slog.Time("expiry", r.token.Expiry))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Format implements fmt.Formatter.
+func (r RedactedToken) Format(f fmt.State, verb rune) {
+	if r.token == nil {
+		_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), r.token)
+		return
+	}
+	// Copy only the exported fields: the unexported raw response may hold
+	// the token too.
+	_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), &oauth2.Token{
+		AccessToken:  redactSecret(r.token.AccessToken),
+		TokenType:    r.token.TokenType,
+		RefreshToken: redactSecret(r.token.RefreshToken),
+		Expiry:       r.token.Expiry,
+		ExpiresIn:    r.token.ExpiresIn,
+	})
+}
+
+// RedactAttr is a slog.HandlerOptions.ReplaceAttr function that redacts
+// oauth2.Token values logged directly, by mistake or otherwise:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+//		ReplaceAttr: githubauth.RedactAttr,
+//	}))
+//
+// InstallationToken values redact themselves and need no handler support.
+func RedactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	switch v := a.Value.Any().(type) {
+	case *oauth2.Token:
+		return slog.Attr{Key: a.Key, Value: Redact(v).LogValue()}
+	case oauth2.Token:
+		return slog.Attr{Key: a.Key, Value: Redact(&v).LogValue()}
+	}
+	return a
+}
+
+// withReuseLogger sets the logger used by ReuseTokenSourceWithSkew to report
+// token store problems, which never fail Token().
+func withReuseLogger(logger *slog.Logger) ReuseTokenSourceOpt {
+	return func(r *reuseTokenSourceWithSkew) {
+		r.logger = logger
+	}
+}
+
+// logRefresh records the outcome of an installation token mint.
+func (t *installationTokenSource) logRefresh(ev RefreshEvent) {
+	attrs := []any{
+		slog.Int64(LogKeyInstallationID, ev.InstallationID),
+		slog.Int(LogKeyAttempt, ev.Attempts),
+		slog.Duration(LogKeyDuration, ev.Duration),
+	}
+	if ev.Err == nil {
+		attrs = append(attrs, slog.Time(LogKeyExpiry, ev.Expiry))
+		t.logger.DebugContext(t.ctx, "installation token minted", attrs...)
+		return
+	}
+
+	attrs = append(attrs, slog.String(LogKeyErrorClass, ev.ErrorClass.String()))
+	var apiErr *APIError
+	if errors.As(ev.Err, &apiErr) {
+		attrs = append(attrs, slog.Int(LogKeyStatusCode, apiErr.StatusCode), slog.String(LogKeyRequestID, apiErr.RequestID))
+	}
+	attrs = append(attrs, slog.Any(LogKeyError, ev.Err))
+	t.logger.ErrorContext(t.ctx, "installation token mint failed", attrs...)
+}
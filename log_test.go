@@ -0,0 +1,150 @@
+package githubauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestWithLogger(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0", "X-GitHub-Request-Id": "ABCD:1234"}},
+		{status: http.StatusCreated, writeToken: true},
+		{status: http.StatusNotFound, body: `{"message":"Not Found"}`},
+	}, &attempts))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ts := NewInstallationTokenSource(42, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithLogger(logger),
+		WithInstallationExpirySkew(2*time.Hour), // every call refreshes
+	)
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("second Token() err = nil, want 404")
+	}
+
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 3 {
+		t.Fatalf("log records = %d, want 3 (throttle, minted, failed):\n%s", len(records), buf.String())
+	}
+
+	throttled, minted, failed := records[0], records[1], records[2]
+	if throttled["level"] != "WARN" || throttled[LogKeyRequestID] != "ABCD:1234" || throttled[LogKeyStatusCode] != float64(429) {
+		t.Errorf("throttle record = %v", throttled)
+	}
+	if _, ok := throttled[LogKeyRetryDelay]; !ok {
+		t.Errorf("throttle record missing %s: %v", LogKeyRetryDelay, throttled)
+	}
+	if minted["level"] != "DEBUG" || minted[LogKeyInstallationID] != float64(42) || minted[LogKeyAttempt] != float64(2) {
+		t.Errorf("minted record = %v", minted)
+	}
+	if failed["level"] != "ERROR" || failed[LogKeyErrorClass] != "other" || failed[LogKeyStatusCode] != float64(404) {
+		t.Errorf("failed record = %v", failed)
+	}
+	if strings.Contains(buf.String(), "test-token") {
+		t.Errorf("log output contains the token value:\n%s", buf.String())
+	}
+}
+
+func TestInstallationToken_Redaction(t *testing.T) {
+	tok := InstallationToken{Token: "ghs_supersecretvalue", ExpiresAt: time.Now()}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, v := range []any{tok, &tok} {
+			out := fmt.Sprintf(format, v)
+			if strings.Contains(out, "supersecret") {
+				t.Errorf("Sprintf(%q, %T) = %q, leaks the token", format, v, out)
+			}
+			if !strings.Contains(out, "ghs_"+redactedMarker) {
+				t.Errorf("Sprintf(%q, %T) = %q, want redacted marker", format, v, out)
+			}
+		}
+	}
+
+	if out := fmt.Sprintf("%#v", tok); !strings.HasPrefix(out, "githubauth.InstallationToken{") {
+		t.Errorf("Sprintf(%%#v) = %q, want the InstallationToken type name", out)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("minted", "token", &tok)
+	if strings.Contains(buf.String(), "supersecret") {
+		t.Errorf("slog output leaks the token: %s", buf.String())
+	}
+
+	// JSON encoding is the wire format and must stay intact.
+	b, err := json.Marshal(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "ghs_supersecretvalue") {
+		t.Errorf("json.Marshal() = %s, want the token preserved", b)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tok := (&oauth2.Token{
+		AccessToken:  "gho_accesssecret",
+		RefreshToken: "ghr_refreshsecret",
+		TokenType:    "Bearer",
+	}).WithExtra(map[string]any{"access_token": "gho_accesssecret"})
+
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		out := fmt.Sprintf(format, Redact(tok))
+		if strings.Contains(out, "secret") {
+			t.Errorf("Sprintf(%q) = %q, leaks a secret", format, out)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: RedactAttr}))
+	logger.Info("wrapped", "token", Redact(tok))
+	logger.Info("by mistake", "token", tok, "copy", *tok)
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("slog output leaks a secret:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "gho_"+redactedMarker) {
+		t.Errorf("slog output = %s, want redacted marker", buf.String())
+	}
+
+	if got := fmt.Sprint(Redact(nil)); got != "<nil>" {
+		t.Errorf("Sprint(Redact(nil)) = %q, want <nil>", got)
+	}
+}
+
+func Test_redactSecret(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"ghs_abc":                  "ghs_" + redactedMarker,
+		"github_pat_11ABC_xyz":     "github_pat_" + redactedMarker,
+		"eyJhbGciOiJSUzI1NiJ9.x.y": redactedMarker,
+		"plain":                    redactedMarker,
+	}
+	for in, want := range tests {
+		if got := redactSecret(in); got != want {
+			t.Errorf("redactSecret(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package githubauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics counts token activity using only the standard library. Attach it
+// to token sources through its hooks, then export it through expvar (Metrics
+// implements expvar.Var) or in the Prometheus text format with Handler or
+// WritePrometheus:
+//
+//	m := githubauth.NewMetrics()
+//	expvar.Publish("githubauth", m)
+//	http.Handle("/metrics", m.Handler())
+//
+//	appSrc, err := githubauth.NewApplicationTokenSource(appID, privateKey,
+//		githubauth.WithApplicationHooks(m.ApplicationHooks()))
+//	installationSrc := githubauth.NewInstallationTokenSource(installationID, appSrc,
+//		githubauth.WithInstallationHooks(m.InstallationHooks()))
+//
+// One Metrics may be shared by any number of token sources; their counts are
+// aggregated. Metrics is safe for concurrent use.
+type Metrics struct {
+	installationHits   atomic.Int64
+	installationMisses atomic.Int64
+	installationMints  atomic.Int64
+	installationErrors atomic.Int64
+	throttles          atomic.Int64
+	retries            atomic.Int64
+
+	jwtHits      atomic.Int64
+	jwtMisses    atomic.Int64
+	jwtSigns     atomic.Int64
+	signerErrors atomic.Int64
+}
+
+// MetricsSnapshot is a point-in-time copy of the counters of a Metrics.
+type MetricsSnapshot struct {
+	// InstallationCacheHits and InstallationCacheMisses count installation
+	// token cache lookups; a token taken from a TokenStore is a hit.
+	InstallationCacheHits   int64 `json:"installation_cache_hits"`
+	InstallationCacheMisses int64 `json:"installation_cache_misses"`
+	// InstallationMints counts installation tokens minted successfully and
+	// InstallationMintErrors failed mints.
+	InstallationMints      int64 `json:"installation_mints"`
+	InstallationMintErrors int64 `json:"installation_mint_errors"`
+	// Throttles counts throttled responses from GitHub, whether or not they
+	// were retried, and Retries the retries made after them.
+	Throttles int64 `json:"throttles"`
+	Retries   int64 `json:"retries"`
+	// JWTCacheHits and JWTCacheMisses count App JWT cache lookups.
+	JWTCacheHits   int64 `json:"jwt_cache_hits"`
+	JWTCacheMisses int64 `json:"jwt_cache_misses"`
+	// JWTSigns counts App JWTs signed successfully and SignerErrors failed
+	// signatures.
+	JWTSigns     int64 `json:"jwt_signs"`
+	SignerErrors int64 `json:"signer_errors"`
+}
+
+// NewMetrics returns a Metrics with all counters at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// InstallationHooks returns hooks counting installation token mints,
+// throttles, retries and cache lookups. Register them with
+// WithInstallationHooks.
+func (m *Metrics) InstallationHooks() Hooks {
+	return Hooks{
+		OnRefresh: func(ev RefreshEvent) {
+			m.installationMints.Add(1)
+			m.countRetries(ev)
+		},
+		OnError: func(ev RefreshEvent) {
+			m.installationErrors.Add(1)
+			m.countRetries(ev)
+			if ev.ErrorClass == ErrorClassThrottled {
+				m.throttles.Add(1)
+			}
+		},
+		OnCacheLookup: func(ev CacheEvent) {
+			countLookup(ev, &m.installationHits, &m.installationMisses)
+		},
+	}
+}
+
+// ApplicationHooks returns hooks counting App JWT signatures, signer errors
+// and cache lookups. Register them with WithApplicationHooks.
+func (m *Metrics) ApplicationHooks() Hooks {
+	return Hooks{
+		OnRefresh: func(RefreshEvent) { m.jwtSigns.Add(1) },
+		OnError:   func(RefreshEvent) { m.signerErrors.Add(1) },
+		OnCacheLookup: func(ev CacheEvent) {
+			countLookup(ev, &m.jwtHits, &m.jwtMisses)
+		},
+	}
+}
+
+// countRetries counts the retries of a mint. A retry is only made after a
+// throttled response, so each one also counts a throttle.
+func (m *Metrics) countRetries(ev RefreshEvent) {
+	if n := int64(ev.Attempts - 1); n > 0 {
+		m.retries.Add(n)
+		m.throttles.Add(n)
+	}
+}
+
+func countLookup(ev CacheEvent, hits, misses *atomic.Int64) {
+	if ev.Hit {
+		hits.Add(1)
+	} else {
+		misses.Add(1)
+	}
+}
+
+// Snapshot returns the current counter values.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		InstallationCacheHits:   m.installationHits.Load(),
+		InstallationCacheMisses: m.installationMisses.Load(),
+		InstallationMints:       m.installationMints.Load(),
+		InstallationMintErrors:  m.installationErrors.Load(),
+		Throttles:               m.throttles.Load(),
+		Retries:                 m.retries.Load(),
+		JWTCacheHits:            m.jwtHits.Load(),
+		JWTCacheMisses:          m.jwtMisses.Load(),
+		JWTSigns:                m.jwtSigns.Load(),
+		SignerErrors:            m.signerErrors.Load(),
+	}
+}
+
+// String implements expvar.Var, returning the snapshot as JSON.
+func (m *Metrics) String() string {
+	b, _ := json.Marshal(m.Snapshot())
+	return string(b)
+}
+
+// WritePrometheus writes the counters to w in the Prometheus text exposition
+// format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	families := []struct {
+		name, help string
+		samples    []promSample
+	}{
+		{"githubauth_cache_lookups_total", "Token cache lookups by token type and result.", []promSample{
+			{`token="installation",result="hit"`, s.InstallationCacheHits},
+			{`token="installation",result="miss"`, s.InstallationCacheMisses},
+			{`token="app_jwt",result="hit"`, s.JWTCacheHits},
+			{`token="app_jwt",result="miss"`, s.JWTCacheMisses},
+		}},
+		{"githubauth_mints_total", "Tokens minted or signed successfully, by token type.", []promSample{
+			{`token="installation"`, s.InstallationMints},
+			{`token="app_jwt"`, s.JWTSigns},
+		}},
+		{"githubauth_mint_errors_total", "Failed installation token mints.", []promSample{
+			{"", s.InstallationMintErrors},
+		}},
+		{"githubauth_throttles_total", "Throttled responses from GitHub when minting installation tokens.", []promSample{
+			{"", s.Throttles},
+		}},
+		{"githubauth_retries_total", "Installation token requests retried after throttling.", []promSample{
+			{"", s.Retries},
+		}},
+		{"githubauth_signer_errors_total", "Failed App JWT signatures.", []promSample{
+			{"", s.SignerErrors},
+		}},
+	}
+
+	for _, f := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", f.name, f.help, f.name); err != nil {
+			return err
+		}
+		for _, sample := range f.samples {
+			labels := ""
+			if sample.labels != "" {
+				labels = "{" + sample.labels + "}"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s %d\n", f.name, labels, sample.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type promSample struct {
+	labels string
+	value  int64
+}
+
+// Handler returns an http.Handler serving the counters in the Prometheus
+// text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
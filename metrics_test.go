@@ -0,0 +1,131 @@
+package githubauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetrics_InstallationHooks(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(throttleHandler(t, []throttleResponse{
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}},
+		{status: http.StatusCreated, writeToken: true},
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}},
+		{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}},
+	}, &attempts))
+	defer server.Close()
+
+	m := NewMetrics()
+	ts := NewInstallationTokenSource(1, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(m.InstallationHooks()),
+	)
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second source sharing the server is throttled twice and gives up.
+	failing := NewInstallationTokenSource(2, oauth2StaticSource{accessToken: "jwt"},
+		WithBaseURL(server.URL),
+		WithInstallationHooks(m.InstallationHooks()),
+	)
+	if _, err := failing.Token(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Token() err = %v, want ErrRateLimited", err)
+	}
+
+	want := MetricsSnapshot{
+		InstallationCacheHits:   1,
+		InstallationCacheMisses: 2,
+		InstallationMints:       1,
+		InstallationMintErrors:  1,
+		Throttles:               3,
+		Retries:                 2,
+	}
+	if got := m.Snapshot(); got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetrics_ApplicationHooks(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &stubSigner{
+		pub: &key.PublicKey,
+		signFn: func(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+			return nil, errors.New("kms unavailable")
+		},
+	}
+
+	m := NewMetrics()
+	ts, err := NewApplicationTokenSourceFromSigner(int64(1), signer, WithApplicationHooks(m.ApplicationHooks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("Token() err = nil, want signer error")
+	}
+
+	signer.signFn = func(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+		return key.Sign(r, digest, opts)
+	}
+	for range 2 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := MetricsSnapshot{JWTCacheHits: 1, JWTCacheMisses: 2, JWTSigns: 1, SignerErrors: 1}
+	if got := m.Snapshot(); got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetrics_Export(t *testing.T) {
+	m := NewMetrics()
+	hooks := m.InstallationHooks()
+	hooks.OnCacheLookup(CacheEvent{Hit: true})
+	hooks.OnRefresh(RefreshEvent{Attempts: 2, Expiry: time.Now()})
+
+	var snap MetricsSnapshot
+	if err := json.Unmarshal([]byte(m.String()), &snap); err != nil {
+		t.Fatalf("String() is not JSON: %v", err)
+	}
+	if snap != m.Snapshot() {
+		t.Errorf("String() = %+v, want %+v", snap, m.Snapshot())
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE githubauth_cache_lookups_total counter",
+		`githubauth_cache_lookups_total{token="installation",result="hit"} 1`,
+		`githubauth_cache_lookups_total{token="app_jwt",result="miss"} 0`,
+		`githubauth_mints_total{token="installation"} 1`,
+		"githubauth_throttles_total 1",
+		"githubauth_retries_total 1",
+		"githubauth_signer_errors_total 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("exposition missing %q:\n%s", line, body)
+		}
+	}
+}
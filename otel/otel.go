@@ -0,0 +1,241 @@
+// Package otel instruments go-githubauth token sources with OpenTelemetry.
+//
+// It records a span for every installation token exchange and every App JWT
+// signature, and the following metrics:
+//
+//   - githubauth.token.mint.duration: histogram of mint latency in seconds,
+//     including throttle retries.
+//   - githubauth.token.cache.lookups: counter of token cache lookups, with a
+//     github.cache_hit attribute from which the hit ratio is derived.
+//   - githubauth.rate_limit.remaining: gauge of the X-RateLimit-Remaining
+//     value last reported by GitHub when minting installation tokens.
+//
+// Instrumentation is attached through the hooks of the root package, so the
+// root module does not depend on OpenTelemetry:
+//
+//	appSrc, err := githubauth.NewApplicationTokenSource(appID, privateKey,
+//		otel.ApplicationOption())
+//	installationSrc := githubauth.NewInstallationTokenSource(installationID, appSrc,
+//		otel.InstallationOption())
+//
+// oauth2.TokenSource carries no context, so spans are recorded as root spans
+// unless a parent context is configured with WithContext.
+//
+// Spans are built after the fact from the hook events, with the start time
+// and duration the event reports. The HTTP request that mints the token does
+// not run under the span's context, so trace context is not propagated into
+// the token exchange and HTTP client instrumentation such as otelhttp
+// records the request in a separate trace.
+package otel
+
+import (
+	"context"
+	"time"
+
+	githubauth "github.com/jferrl/go-githubauth"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/jferrl/go-githubauth/otel"
+
+// Span names.
+const (
+	SpanInstallationToken = "githubauth.installation_token"
+	SpanSignJWT           = "githubauth.sign_jwt"
+)
+
+// Attribute keys recorded on spans and metrics. GitHub specific keys match
+// the githubauth.LogKey* log attribute keys.
+const (
+	AttrInstallationID = attribute.Key(githubauth.LogKeyInstallationID)
+	AttrRequestID      = attribute.Key(githubauth.LogKeyRequestID)
+	AttrRetryCount     = attribute.Key("github.retry_count")
+	AttrTokenType      = attribute.Key("github.token_type")
+	AttrCacheHit       = attribute.Key("github.cache_hit")
+	AttrStatusCode     = attribute.Key("http.response.status_code")
+	AttrErrorType      = attribute.Key("error.type")
+)
+
+// Values of AttrTokenType.
+const (
+	tokenTypeInstallation = "installation"
+	tokenTypeJWT          = "app_jwt"
+)
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	ctx            context.Context
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans. Defaults
+// to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.tracerProvider = tp
+		}
+	}
+}
+
+// WithMeterProvider sets the MeterProvider used to create instruments.
+// Defaults to the global provider.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		if mp != nil {
+			c.meterProvider = mp
+		}
+	}
+}
+
+// WithContext sets the context spans are started from, making them children
+// of the span it carries, and the context metrics are recorded with.
+// Defaults to context.Background().
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		if ctx != nil {
+			c.ctx = ctx
+		}
+	}
+}
+
+// InstallationHooks returns hooks recording installation token mints and
+// cache lookups. Register them with githubauth.WithInstallationHooks, or use
+// InstallationOption.
+func InstallationHooks(opts ...Option) githubauth.Hooks {
+	return newInstrumentation(SpanInstallationToken, tokenTypeInstallation, opts).hooks()
+}
+
+// ApplicationHooks returns hooks recording App JWT signatures and cache
+// lookups. Register them with githubauth.WithApplicationHooks, or use
+// ApplicationOption.
+func ApplicationHooks(opts ...Option) githubauth.Hooks {
+	return newInstrumentation(SpanSignJWT, tokenTypeJWT, opts).hooks()
+}
+
+// InstallationOption instruments a source created by
+// githubauth.NewInstallationTokenSource.
+func InstallationOption(opts ...Option) githubauth.InstallationTokenSourceOpt {
+	return githubauth.WithInstallationHooks(InstallationHooks(opts...))
+}
+
+// ApplicationOption instruments a source created by
+// githubauth.NewApplicationTokenSource or
+// githubauth.NewApplicationTokenSourceFromSigner.
+func ApplicationOption(opts ...Option) githubauth.ApplicationTokenOpt {
+	return githubauth.WithApplicationHooks(ApplicationHooks(opts...))
+}
+
+type instrumentation struct {
+	ctx       context.Context
+	tracer    trace.Tracer
+	spanName  string
+	tokenType attribute.KeyValue
+
+	duration  metric.Float64Histogram
+	lookups   metric.Int64Counter
+	remaining metric.Int64Gauge
+}
+
+func newInstrumentation(spanName, tokenType string, opts []Option) *instrumentation {
+	c := config{
+		tracerProvider: otelapi.GetTracerProvider(),
+		meterProvider:  otelapi.GetMeterProvider(),
+		ctx:            context.Background(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(ScopeName)
+	in := &instrumentation{
+		ctx:       c.ctx,
+		tracer:    c.tracerProvider.Tracer(ScopeName),
+		spanName:  spanName,
+		tokenType: AttrTokenType.String(tokenType),
+	}
+
+	// Instrument creation only fails on invalid names or units; following
+	// OpenTelemetry convention the error is reported to the global handler
+	// and the returned no-op instrument is used.
+	var err error
+	if in.duration, err = meter.Float64Histogram("githubauth.token.mint.duration",
+		metric.WithDescription("Duration of token mints, including throttle retries."),
+		metric.WithUnit("s")); err != nil {
+		otelapi.Handle(err)
+	}
+	if in.lookups, err = meter.Int64Counter("githubauth.token.cache.lookups",
+		metric.WithDescription("Token cache lookups, by whether the cached token was served."),
+		metric.WithUnit("{lookup}")); err != nil {
+		otelapi.Handle(err)
+	}
+	if in.remaining, err = meter.Int64Gauge("githubauth.rate_limit.remaining",
+		metric.WithDescription("X-RateLimit-Remaining last reported by GitHub when minting installation tokens."),
+		metric.WithUnit("{request}")); err != nil {
+		otelapi.Handle(err)
+	}
+	return in
+}
+
+func (in *instrumentation) hooks() githubauth.Hooks {
+	return githubauth.Hooks{
+		OnRefresh:     in.refresh,
+		OnError:       in.refresh,
+		OnCacheLookup: in.lookup,
+	}
+}
+
+// refresh records a span and metrics for a completed mint. Hooks fire after
+// the mint, so the span is back-dated to the mint's start.
+func (in *instrumentation) refresh(ev githubauth.RefreshEvent) {
+	end := time.Now()
+	start := end.Add(-ev.Duration)
+
+	attrs := []attribute.KeyValue{in.tokenType}
+	if ev.InstallationID != 0 {
+		attrs = append(attrs, AttrInstallationID.Int64(ev.InstallationID))
+	}
+	if ev.Attempts > 1 {
+		attrs = append(attrs, AttrRetryCount.Int(ev.Attempts-1))
+	}
+	if ev.StatusCode != 0 {
+		attrs = append(attrs, AttrStatusCode.Int(ev.StatusCode))
+	}
+	if ev.RequestID != "" {
+		attrs = append(attrs, AttrRequestID.String(ev.RequestID))
+	}
+
+	kind := trace.SpanKindInternal
+	if ev.StatusCode != 0 || ev.ErrorClass == githubauth.ErrorClassNetwork {
+		kind = trace.SpanKindClient
+	}
+	_, span := in.tracer.Start(in.ctx, in.spanName,
+		trace.WithTimestamp(start), trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	if ev.Err != nil {
+		span.SetAttributes(AttrErrorType.String(ev.ErrorClass.String()))
+		span.RecordError(ev.Err, trace.WithTimestamp(end))
+		span.SetStatus(codes.Error, ev.Err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+
+	metricAttrs := []attribute.KeyValue{in.tokenType}
+	if ev.Err != nil {
+		metricAttrs = append(metricAttrs, AttrErrorType.String(ev.ErrorClass.String()))
+	}
+	in.duration.Record(in.ctx, ev.Duration.Seconds(), metric.WithAttributes(metricAttrs...))
+	if ev.RateLimitRemaining >= 0 {
+		in.remaining.Record(in.ctx, int64(ev.RateLimitRemaining))
+	}
+}
+
+func (in *instrumentation) lookup(ev githubauth.CacheEvent) {
+	in.lookups.Add(in.ctx, 1, metric.WithAttributes(in.tokenType, AttrCacheHit.Bool(ev.Hit)))
+}
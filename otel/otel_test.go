@@ -0,0 +1,196 @@
+package otel
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	githubauth "github.com/jferrl/go-githubauth"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/oauth2"
+)
+
+type staticSource string
+
+func (s staticSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: string(s)}, nil
+}
+
+func newProviders() (*tracetest.SpanRecorder, *sdktrace.TracerProvider, *sdkmetric.ManualReader, *sdkmetric.MeterProvider) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	return spans, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		reader, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+}
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	return got
+}
+
+func spanAttrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestInstallationOption(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+			w.Header().Set("X-RateLimit-Remaining", "4321")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(githubauth.InstallationToken{
+				Token:     "ghs_test",
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	spans, tp, reader, mp := newProviders()
+	ts := githubauth.NewInstallationTokenSource(42, staticSource("jwt"),
+		githubauth.WithBaseURL(server.URL),
+		InstallationOption(WithTracerProvider(tp), WithMeterProvider(mp)),
+	)
+	for range 2 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != SpanInstallationToken {
+		t.Errorf("span name = %q, want %q", span.Name(), SpanInstallationToken)
+	}
+	attrs := spanAttrs(span)
+	if got := attrs[AttrInstallationID].AsInt64(); got != 42 {
+		t.Errorf("installation ID = %d, want 42", got)
+	}
+	if got := attrs[AttrStatusCode].AsInt64(); got != http.StatusCreated {
+		t.Errorf("status code = %d, want 201", got)
+	}
+	if got := attrs[AttrRetryCount].AsInt64(); got != 1 {
+		t.Errorf("retry count = %d, want 1", got)
+	}
+	if got := attrs[AttrRequestID].AsString(); got != "ABCD:1234" {
+		t.Errorf("request ID = %q, want ABCD:1234", got)
+	}
+	if span.Status().Code == codes.Error {
+		t.Errorf("span status = %v, want unset", span.Status())
+	}
+
+	metrics := collect(t, reader)
+	hist, ok := metrics["githubauth.token.mint.duration"].(metricdata.Histogram[float64])
+	if !ok || len(hist.DataPoints) != 1 || hist.DataPoints[0].Count != 1 {
+		t.Errorf("mint duration = %+v, want one recorded mint", metrics["githubauth.token.mint.duration"])
+	}
+	gauge, ok := metrics["githubauth.rate_limit.remaining"].(metricdata.Gauge[int64])
+	if !ok || len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 4321 {
+		t.Errorf("rate limit remaining = %+v, want 4321", metrics["githubauth.rate_limit.remaining"])
+	}
+	sum, ok := metrics["githubauth.token.cache.lookups"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("cache lookups = %+v, want a sum", metrics["githubauth.token.cache.lookups"])
+	}
+	lookups := make(map[bool]int64)
+	for _, dp := range sum.DataPoints {
+		hit, _ := dp.Attributes.Value(AttrCacheHit)
+		lookups[hit.AsBool()] += dp.Value
+	}
+	if lookups[true] != 1 || lookups[false] != 1 {
+		t.Errorf("cache lookups = %v, want one hit and one miss", lookups)
+	}
+}
+
+func TestInstallationOption_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	spans, tp, reader, mp := newProviders()
+	ts := githubauth.NewInstallationTokenSource(7, staticSource("jwt"),
+		githubauth.WithBaseURL(server.URL),
+		InstallationOption(WithTracerProvider(tp), WithMeterProvider(mp)),
+	)
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("Token() err = nil, want 401")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	if ended[0].Status().Code != codes.Error {
+		t.Errorf("span status = %v, want error", ended[0].Status())
+	}
+	if got := spanAttrs(ended[0])[AttrErrorType].AsString(); got != "auth" {
+		t.Errorf("error type = %q, want auth", got)
+	}
+	if _, ok := collect(t, reader)["githubauth.rate_limit.remaining"]; ok {
+		t.Error("rate limit gauge recorded without X-RateLimit-Remaining header")
+	}
+}
+
+func TestApplicationOption(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	spans, tp, _, mp := newProviders()
+	ts, err := githubauth.NewApplicationTokenSource(int64(1), pemKey,
+		ApplicationOption(WithTracerProvider(tp), WithMeterProvider(mp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != SpanSignJWT {
+		t.Fatalf("spans = %v, want one %s span", ended, SpanSignJWT)
+	}
+	if got := spanAttrs(ended[0])[AttrTokenType].AsString(); got != tokenTypeJWT {
+		t.Errorf("token type = %q, want %q", got, tokenTypeJWT)
+	}
+}
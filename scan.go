@@ -0,0 +1,179 @@
+package githubauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// TokenKindApplicationJWT is reported by ScanTokens for GitHub App JWTs.
+// ParseToken never returns it.
+const TokenKindApplicationJWT TokenKind = -1
+
+// tokenCandidate matches strings shaped like GitHub tokens or JWTs. Matches
+// are confirmed with ParseToken or DecodeApplicationJWT before being
+// reported, so random strings of the right shape are not redacted.
+var tokenCandidate = regexp.MustCompile(
+	`\b(?:gh[pousr]_[0-9A-Za-z]{36}|github_pat_[0-9A-Za-z]{22}_[0-9A-Za-z]{59})\b` +
+		`|\beyJ[0-9A-Za-z_-]+\.eyJ[0-9A-Za-z_-]+\.[0-9A-Za-z_-]+`)
+
+// TokenMatch is a secret found by ScanTokens.
+type TokenMatch struct {
+	// Kind is the kind of the token, TokenKindApplicationJWT for App JWTs.
+	Kind TokenKind
+	// Start and End are the byte offsets of the token in the scanned text.
+	Start, End int
+	// Hash is a short hexadecimal SHA-256 prefix of the token. It is stable,
+	// so redacted occurrences of the same token can be correlated across
+	// logs, but does not reveal the token.
+	Hash string
+}
+
+// Redacted returns the replacement RedactTokens substitutes for the token:
+// its prefix, if any, followed by "[REDACTED:<hash>]".
+func (m TokenMatch) Redacted() string {
+	prefix := ""
+	if m.Kind != TokenKindApplicationJWT {
+		prefix = m.Kind.String()
+	}
+	return prefix + "[REDACTED:" + m.Hash + "]"
+}
+
+// ScanTokens returns the GitHub tokens and App JWTs found in text, in order.
+// Tokens are recognized by their prefix and confirmed with ParseToken
+// (including the checksum where GitHub embeds one); JWTs are confirmed with
+// DecodeApplicationJWT. The check is offline: a reported token may have been
+// revoked.
+func ScanTokens(text string) []TokenMatch {
+	return scanTokens([]byte(text))
+}
+
+// RedactTokens returns text with every token reported by ScanTokens replaced
+// by TokenMatch.Redacted.
+func RedactTokens(text string) string {
+	return string(redactTokens([]byte(text)))
+}
+
+func scanTokens(b []byte) []TokenMatch {
+	var matches []TokenMatch
+	for _, loc := range tokenCandidate.FindAllIndex(b, -1) {
+		token := string(b[loc[0]:loc[1]])
+
+		var kind TokenKind
+		if token[0] == 'e' {
+			if _, err := DecodeApplicationJWT(token); err != nil {
+				continue
+			}
+			kind = TokenKindApplicationJWT
+		} else {
+			var err error
+			if kind, err = ParseToken(token); err != nil {
+				continue
+			}
+		}
+
+		sum := sha256.Sum256([]byte(token))
+		matches = append(matches, TokenMatch{
+			Kind:  kind,
+			Start: loc[0],
+			End:   loc[1],
+			Hash:  hex.EncodeToString(sum[:6]),
+		})
+	}
+	return matches
+}
+
+func redactTokens(b []byte) []byte {
+	matches := scanTokens(b)
+	if len(matches) == 0 {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	last := 0
+	for _, m := range matches {
+		out = append(out, b[last:m.Start]...)
+		out = append(out, m.Redacted()...)
+		last = m.End
+	}
+	return append(out, b[last:]...)
+}
+
+// maxRedactBuffer bounds how much a RedactingWriter holds back waiting for
+// the end of a possible token. It is far longer than any GitHub token or
+// App JWT.
+const maxRedactBuffer = 64 << 10
+
+// RedactingWriter is an io.Writer that removes GitHub tokens and App JWTs
+// from everything written to it before passing it on, replacing them the way
+// RedactTokens does. Wrap log sinks and HTTP dump output with it:
+//
+//	w := githubauth.NewRedactingWriter(os.Stderr)
+//	logger := slog.New(slog.NewTextHandler(w, nil))
+//
+// A token may be split across Write calls, so trailing bytes that could
+// start a token are held back until a following write ends it; call Flush
+// to emit them. Text written one line at a time, as by log/slog and the log
+// package, is passed on immediately. RedactingWriter is safe for concurrent
+// use.
+type RedactingWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewRedactingWriter returns a RedactingWriter writing to w.
+func NewRedactingWriter(w io.Writer) *RedactingWriter {
+	return &RedactingWriter{w: w}
+}
+
+// Write redacts and writes p, holding back a trailing partial token. It
+// reports len(p) when the redacted output was written. When the underlying
+// writer fails, none of p is accepted: Write returns 0 and the error, and p
+// may be written again.
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held := len(r.buf)
+	r.buf = append(r.buf, p...)
+	// Tokens never contain bytes outside their alphabet, so everything up to
+	// the last such byte can be redacted and written.
+	n := len(r.buf)
+	for n > 0 && isTokenByte(r.buf[n-1]) {
+		n--
+	}
+	if len(r.buf)-n > maxRedactBuffer {
+		n = len(r.buf)
+	}
+	if err := r.flush(n); err != nil {
+		r.buf = r.buf[:held]
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush redacts and writes any bytes held back by Write.
+func (r *RedactingWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush(len(r.buf))
+}
+
+func (r *RedactingWriter) flush(n int) error {
+	if n == 0 {
+		return nil
+	}
+	if _, err := r.w.Write(redactTokens(r.buf[:n])); err != nil {
+		return err
+	}
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+	return nil
+}
+
+// isTokenByte reports whether c can appear in a GitHub token or a JWT.
+func isTokenByte(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' ||
+		c == '_' || c == '-' || c == '.'
+}
@@ -0,0 +1,249 @@
+package githubauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// defaultTokenStoreLease bounds how long a TokenStore lock is held while a
+// token is minted, and how long other holders wait for it. It covers a mint
+// that includes the maximum throttle retry sleep.
+const defaultTokenStoreLease = 90 * time.Second
+
+// ErrTokenNotFound is returned by TokenStore.Get when no token is stored
+// under the key.
+var ErrTokenNotFound = errors.New("token not found in store")
+
+// TokenKey identifies a token in a TokenStore.
+type TokenKey struct {
+	// App is the GitHub App the token belongs to, as App ID or Client ID.
+	App string
+	// InstallationID is the installation the token was minted for, or zero
+	// for tokens that are not bound to an installation.
+	InstallationID int64
+	// Scope distinguishes tokens for the same App and installation that were
+	// minted against a different API endpoint or with different permissions.
+	Scope string
+}
+
+// String returns a stable, human-readable form of the key.
+func (k TokenKey) String() string {
+	return "app/" + k.App + "/installation/" + strconv.FormatInt(k.InstallationID, 10) + "/" + k.Scope
+}
+
+// TokenStore persists tokens so cooperating token sources, possibly in
+// different processes, can share one unexpired token instead of each minting
+// their own. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Get returns the token stored under key, or ErrTokenNotFound. Expired
+	// tokens may be returned; callers check expiry themselves.
+	Get(ctx context.Context, key TokenKey) (*oauth2.Token, error)
+	// Put stores tok under key, replacing any previous token.
+	Put(ctx context.Context, key TokenKey, tok *oauth2.Token) error
+	// Delete removes the token stored under key. Deleting a missing key is
+	// not an error.
+	Delete(ctx context.Context, key TokenKey) error
+	// Lock acquires an exclusive lock on key, waiting until it is free or ctx
+	// is done. The lock is released by calling unlock, or automatically once
+	// lease has elapsed so a crashed holder cannot block others forever.
+	Lock(ctx context.Context, key TokenKey, lease time.Duration) (unlock func(), err error)
+}
+
+// ReuseTokenSourceOpt is a functional option for ReuseTokenSourceWithSkew.
+type ReuseTokenSourceOpt func(*reuseTokenSourceWithSkew)
+
+// WithTokenStore makes the cache consult store before minting. When the
+// in-memory token needs refresh, the wrapper first looks for a fresher token
+// under key; failing that it takes the store lock, checks again (another
+// holder may have minted in the meantime), mints from the underlying source
+// and publishes the result. Cooperating processes therefore reuse one
+// unexpired token and only one of them mints the next.
+//
+// Store failures never fail Token(): if the store cannot be read, locked or
+// written within the lock lease, the wrapper mints on its own.
+func WithTokenStore(store TokenStore, key TokenKey) ReuseTokenSourceOpt {
+	return func(r *reuseTokenSourceWithSkew) {
+		r.store = store
+		r.key = key
+	}
+}
+
+// WithInstallationTokenStore shares installation tokens through store, see
+// WithTokenStore. The store key is derived from the App (when the source
+// passed to NewInstallationTokenSource was created by this package), the
+// installation ID, the API base URL and the requested
+// InstallationTokenOptions, so differently scoped tokens never collide.
+func WithInstallationTokenStore(store TokenStore) InstallationTokenSourceOpt {
+	return func(i *installationTokenSource) {
+		i.store = store
+	}
+}
+
+// storeKey derives the TokenStore key for the installation token source.
+func (t *installationTokenSource) storeKey() TokenKey {
+	key := TokenKey{
+		InstallationID: t.id,
+		Scope:          t.client.baseURL.String(),
+	}
+	if app, ok := applicationSourceOf(t.src); ok {
+		key.App = app.issuer
+	}
+	if t.opts != nil {
+		if b, err := json.Marshal(t.opts); err == nil {
+			sum := sha256.Sum256(b)
+			key.Scope += "#" + hex.EncodeToString(sum[:8])
+		}
+	}
+	return key
+}
+
+// tokenFromStore refreshes r.t through the configured store, reporting
+// whether the token was found in the store rather than minted. The caller
+// holds r.refreshMu but not r.mu, so callers served the cached token are not
+// held up while the store lock is awaited.
+func (r *reuseTokenSourceWithSkew) tokenFromStore(gen uint64) (*oauth2.Token, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTokenStoreLease)
+	defer cancel()
+
+	if t, err := r.store.Get(ctx, r.key); err == nil && r.fresh(t) {
+		r.cache(t, gen)
+		return t, true, nil
+	}
+
+	unlock, err := r.store.Lock(ctx, r.key, defaultTokenStoreLease)
+	if err != nil {
+		r.logger.Warn("token store lock unavailable; minting without coordination",
+			slog.Int64(LogKeyInstallationID, r.key.InstallationID), slog.Any(LogKeyError, err))
+		t, err := r.mint(gen)
+		return t, false, err
+	}
+	defer unlock()
+
+	if t, err := r.store.Get(ctx, r.key); err == nil && r.fresh(t) {
+		r.cache(t, gen)
+		return t, true, nil
+	}
+
+	t, err := r.mint(gen)
+	if err != nil {
+		return nil, false, err
+	}
+	// A token minted across an invalidation may carry revoked permissions
+	// or repositories; it is returned to this caller but never shared.
+	if _, now := r.current(); now != gen {
+		return t, false, nil
+	}
+	if err := r.store.Put(ctx, r.key, t); err != nil {
+		r.logger.Warn("failed to publish token to token store",
+			slog.Int64(LogKeyInstallationID, r.key.InstallationID), slog.Any(LogKeyError, err))
+	}
+	return t, false, nil
+}
+
+// MemoryTokenStore is an in-process TokenStore. It lets several token
+// sources in one process share tokens and is useful in tests. The zero value
+// is not usable; create one with NewMemoryTokenStore.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[TokenKey]oauth2.Token
+	locks  map[TokenKey]*memoryLease
+}
+
+type memoryLease struct {
+	expires  time.Time
+	released chan struct{}
+	once     sync.Once
+}
+
+func (l *memoryLease) release() {
+	l.once.Do(func() { close(l.released) })
+}
+
+// NewMemoryTokenStore returns an empty in-memory TokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[TokenKey]oauth2.Token),
+		locks:  make(map[TokenKey]*memoryLease),
+	}
+}
+
+// Get implements TokenStore.
+func (s *MemoryTokenStore) Get(_ context.Context, key TokenKey) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &t, nil
+}
+
+// Put implements TokenStore.
+func (s *MemoryTokenStore) Put(_ context.Context, key TokenKey, tok *oauth2.Token) error {
+	if tok == nil {
+		return errors.New("token must not be nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = *tok
+	return nil
+}
+
+// Delete implements TokenStore.
+func (s *MemoryTokenStore) Delete(_ context.Context, key TokenKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
+
+// Lock implements TokenStore.
+func (s *MemoryTokenStore) Lock(ctx context.Context, key TokenKey, lease time.Duration) (func(), error) {
+	for {
+		s.mu.Lock()
+		held := s.locks[key]
+		if held == nil || !time.Now().Before(held.expires) {
+			l := &memoryLease{
+				expires:  time.Now().Add(lease),
+				released: make(chan struct{}),
+			}
+			s.locks[key] = l
+			s.mu.Unlock()
+
+			timer := time.AfterFunc(lease, func() { s.unlock(key, l) })
+			return func() {
+				timer.Stop()
+				s.unlock(key, l)
+			}, nil
+		}
+		s.mu.Unlock()
+
+		wait := time.NewTimer(time.Until(held.expires))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return nil, ctx.Err()
+		case <-held.released:
+		case <-wait.C:
+		}
+		wait.Stop()
+	}
+}
+
+func (s *MemoryTokenStore) unlock(key TokenKey, l *memoryLease) {
+	s.mu.Lock()
+	if s.locks[key] == l {
+		delete(s.locks, key)
+	}
+	s.mu.Unlock()
+	l.release()
+}
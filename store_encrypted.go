@@ -0,0 +1,202 @@
+package githubauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// sealedTokenType marks tokens written by EncryptedTokenStore to the
+// underlying store.
+const sealedTokenType = "sealed-v1"
+
+// Sentinel errors returned by EncryptedTokenStore.Get. Callers can branch
+// with errors.Is.
+var (
+	// ErrTokenBindingMismatch reports a stored entry sealed for a different
+	// App, installation or scope than the key it was read from.
+	ErrTokenBindingMismatch = errors.New("stored token is bound to a different key")
+	// ErrUnknownEncryptionKey reports a stored entry sealed under a key ID
+	// that is not configured, e.g. a key that was rotated out.
+	ErrUnknownEncryptionKey = errors.New("stored token is sealed with an unknown key")
+)
+
+// EncryptionKey is an AES key used by EncryptedTokenStore.
+type EncryptionKey struct {
+	// ID names the key. It is stored with every sealed entry so entries
+	// written under an older key can still be opened during rotation.
+	ID string
+	// Key is the AES-128, AES-192 or AES-256 key (16, 24 or 32 bytes).
+	Key []byte
+}
+
+// EncryptedTokenStore wraps a TokenStore so tokens, including refresh
+// tokens, are sealed with AES-GCM before they reach it. Besides the token
+// itself, the App, installation, scope, key ID and expiry are authenticated,
+// so an entry copied under a different key or with an altered expiry is
+// refused rather than served.
+type EncryptedTokenStore struct {
+	store   TokenStore
+	primary EncryptionKey
+	aeads   map[string]cipher.AEAD
+}
+
+// sealedToken is the envelope stored in the underlying store's AccessToken.
+type sealedToken struct {
+	KeyID          string `json:"kid"`
+	App            string `json:"app"`
+	InstallationID int64  `json:"installation_id"`
+	Scope          string `json:"scope"`
+	Nonce          []byte `json:"nonce"`
+	Ciphertext     []byte `json:"ciphertext"`
+}
+
+// NewEncryptedTokenStore returns a TokenStore that seals tokens with primary
+// before writing them to store. Entries sealed with any of the previous keys
+// can still be read, which allows rotating keys without discarding cached
+// tokens: add the new key as primary and keep the old one in previous until
+// its entries have expired.
+func NewEncryptedTokenStore(store TokenStore, primary EncryptionKey, previous ...EncryptionKey) (*EncryptedTokenStore, error) {
+	if store == nil {
+		return nil, errors.New("token store is required")
+	}
+
+	s := &EncryptedTokenStore{
+		store:   store,
+		primary: primary,
+		aeads:   make(map[string]cipher.AEAD, 1+len(previous)),
+	}
+	for _, k := range append([]EncryptionKey{primary}, previous...) {
+		if k.ID == "" {
+			return nil, errors.New("encryption key ID is required")
+		}
+		if _, dup := s.aeads[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate encryption key ID %q", k.ID)
+		}
+		block, err := aes.NewCipher(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", k.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", k.ID, err)
+		}
+		s.aeads[k.ID] = aead
+	}
+	return s, nil
+}
+
+// Get implements TokenStore.
+func (s *EncryptedTokenStore) Get(ctx context.Context, key TokenKey) (*oauth2.Token, error) {
+	stored, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if stored.TokenType != sealedTokenType {
+		return nil, errors.New("stored token is not sealed")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(stored.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed token: %w", err)
+	}
+	var env sealedToken
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode sealed token: %w", err)
+	}
+
+	if env.App != key.App || env.InstallationID != key.InstallationID || env.Scope != key.Scope {
+		return nil, fmt.Errorf("%w: sealed for app %q installation %d, read as app %q installation %d",
+			ErrTokenBindingMismatch, env.App, env.InstallationID, key.App, key.InstallationID)
+	}
+
+	aead, ok := s.aeads[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, env.KeyID)
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, sealedTokenAAD(env.KeyID, key, stored.Expiry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sealed token: %w", err)
+	}
+
+	var tok oauth2.Token
+	if err := json.Unmarshal(plaintext, &tok); err != nil {
+		return nil, fmt.Errorf("failed to decode token: %w", err)
+	}
+	return &tok, nil
+}
+
+// Put implements TokenStore.
+func (s *EncryptedTokenStore) Put(ctx context.Context, key TokenKey, tok *oauth2.Token) error {
+	if tok == nil {
+		return errors.New("token must not be nil")
+	}
+
+	plaintext, err := json.Marshal(tok)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	aead := s.aeads[s.primary.ID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	env := sealedToken{
+		KeyID:          s.primary.ID,
+		App:            key.App,
+		InstallationID: key.InstallationID,
+		Scope:          key.Scope,
+		Nonce:          nonce,
+		Ciphertext:     aead.Seal(nil, nonce, plaintext, sealedTokenAAD(s.primary.ID, key, tok.Expiry)),
+	}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to encode sealed token: %w", err)
+	}
+
+	// Expiry stays visible to the underlying store (for eviction policies)
+	// and is authenticated through the additional data.
+	return s.store.Put(ctx, key, &oauth2.Token{
+		AccessToken: base64.RawURLEncoding.EncodeToString(raw),
+		TokenType:   sealedTokenType,
+		Expiry:      tok.Expiry,
+	})
+}
+
+// Delete implements TokenStore.
+func (s *EncryptedTokenStore) Delete(ctx context.Context, key TokenKey) error {
+	return s.store.Delete(ctx, key)
+}
+
+// Lock implements TokenStore.
+func (s *EncryptedTokenStore) Lock(ctx context.Context, key TokenKey, lease time.Duration) (func(), error) {
+	return s.store.Lock(ctx, key, lease)
+}
+
+// sealedTokenAAD binds a sealed entry to its key ID, store key and expiry.
+// The expiry is bound in whole seconds, since underlying stores such as
+// databases or caches may keep it with less than nanosecond precision.
+func sealedTokenAAD(keyID string, key TokenKey, expiry time.Time) []byte {
+	var exp int64
+	if !expiry.IsZero() {
+		exp = expiry.Unix()
+	}
+	aad, _ := json.Marshal(struct {
+		Type   string `json:"typ"`
+		KeyID  string `json:"kid"`
+		Key    string `json:"key"`
+		Expiry int64  `json:"exp"`
+	}{sealedTokenType, keyID, key.String(), exp})
+	return aad
+}
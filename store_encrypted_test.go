@@ -0,0 +1,166 @@
+package githubauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestEncryptedTokenStore(t *testing.T) {
+	ctx := context.Background()
+	oldKey := EncryptionKey{ID: "2025-01", Key: bytes.Repeat([]byte{1}, 32)}
+	newKey := EncryptionKey{ID: "2026-01", Key: bytes.Repeat([]byte{2}, 32)}
+	key := TokenKey{App: "Iv1.abc", InstallationID: 7, Scope: "https://api.github.com/"}
+	tok := &oauth2.Token{
+		AccessToken:  "ghu_secret",
+		RefreshToken: "ghr_secret",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour).Round(0),
+	}
+
+	dir := t.TempDir()
+	files, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealedOld, err := NewEncryptedTokenStore(files, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sealedOld.Put(ctx, key, tok); err != nil {
+		t.Fatalf("Put() err = %v", err)
+	}
+
+	raw, err := os.ReadFile(files.path(key, ".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "ghu_secret") || strings.Contains(string(raw), "ghr_secret") {
+		t.Fatalf("stored file contains plaintext secrets: %s", raw)
+	}
+
+	t.Run("round trip after rotation", func(t *testing.T) {
+		rotated, err := NewEncryptedTokenStore(files, newKey, oldKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rotated.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get() err = %v", err)
+		}
+		if got.AccessToken != tok.AccessToken || got.RefreshToken != tok.RefreshToken || !got.Expiry.Equal(tok.Expiry) {
+			t.Errorf("Get() = %+v, want %+v", got, tok)
+		}
+	})
+
+	t.Run("retired key", func(t *testing.T) {
+		onlyNew, err := NewEncryptedTokenStore(files, newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := onlyNew.Get(ctx, key); !errors.Is(err, ErrUnknownEncryptionKey) {
+			t.Errorf("Get() err = %v, want ErrUnknownEncryptionKey", err)
+		}
+	})
+
+	t.Run("entry bound to another installation", func(t *testing.T) {
+		mem := NewMemoryTokenStore()
+		sealed, err := NewEncryptedTokenStore(mem, oldKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sealed.Put(ctx, key, tok); err != nil {
+			t.Fatal(err)
+		}
+		// Copy the sealed entry under another installation's key.
+		other := key
+		other.InstallationID = 8
+		entry, _ := mem.Get(ctx, key)
+		_ = mem.Put(ctx, other, entry)
+
+		if _, err := sealed.Get(ctx, other); !errors.Is(err, ErrTokenBindingMismatch) {
+			t.Errorf("Get() err = %v, want ErrTokenBindingMismatch", err)
+		}
+	})
+
+	t.Run("tampered expiry", func(t *testing.T) {
+		mem := NewMemoryTokenStore()
+		sealed, err := NewEncryptedTokenStore(mem, oldKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sealed.Put(ctx, key, tok); err != nil {
+			t.Fatal(err)
+		}
+		entry, _ := mem.Get(ctx, key)
+		entry.Expiry = entry.Expiry.Add(24 * time.Hour)
+		_ = mem.Put(ctx, key, entry)
+
+		if _, err := sealed.Get(ctx, key); err == nil {
+			t.Error("Get() err = nil, want authentication failure")
+		}
+	})
+
+	t.Run("store truncating expiry", func(t *testing.T) {
+		sealed, err := NewEncryptedTokenStore(truncatingStore{NewMemoryTokenStore()}, oldKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sealed.Put(ctx, key, tok); err != nil {
+			t.Fatal(err)
+		}
+		got, err := sealed.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get() err = %v", err)
+		}
+		if !got.Expiry.Equal(tok.Expiry) {
+			t.Errorf("Get() expiry = %v, want %v from the sealed token", got.Expiry, tok.Expiry)
+		}
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		if _, err := sealedOld.Get(ctx, TokenKey{App: "none"}); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Get() err = %v, want ErrTokenNotFound", err)
+		}
+	})
+}
+
+// truncatingStore keeps expiries in whole seconds, like many databases.
+type truncatingStore struct {
+	*MemoryTokenStore
+}
+
+func (s truncatingStore) Put(ctx context.Context, key TokenKey, tok *oauth2.Token) error {
+	stored := *tok
+	stored.Expiry = stored.Expiry.Truncate(time.Second)
+	return s.MemoryTokenStore.Put(ctx, key, &stored)
+}
+
+func TestNewEncryptedTokenStore_Errors(t *testing.T) {
+	valid := EncryptionKey{ID: "k1", Key: make([]byte, 32)}
+	tests := []struct {
+		name     string
+		store    TokenStore
+		primary  EncryptionKey
+		previous []EncryptionKey
+	}{
+		{"nil store", nil, valid, nil},
+		{"missing key ID", NewMemoryTokenStore(), EncryptionKey{Key: make([]byte, 32)}, nil},
+		{"bad key length", NewMemoryTokenStore(), EncryptionKey{ID: "k1", Key: make([]byte, 7)}, nil},
+		{"duplicate key ID", NewMemoryTokenStore(), valid, []EncryptionKey{valid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEncryptedTokenStore(tt.store, tt.primary, tt.previous...); err == nil {
+				t.Error("NewEncryptedTokenStore() err = nil, want error")
+			}
+		})
+	}
+}
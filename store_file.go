@@ -0,0 +1,115 @@
+package githubauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jferrl/go-githubauth/internal/atomicfile"
+	"golang.org/x/oauth2"
+)
+
+// lockPollInterval is how often FileTokenStore.Lock retries a held lock.
+const lockPollInterval = 50 * time.Millisecond
+
+// FileTokenStore is a TokenStore backed by a directory, shared by every
+// process that opens the same directory: replicas on a shared volume, or
+// successive CLI invocations on one machine. Each key is stored in its own
+// file, written atomically with mode 0600. Locks are advisory file locks
+// (flock on Unix), which the operating system releases if the holder dies.
+// Tokens are stored in plaintext; wrap the store with NewEncryptedTokenStore
+// unless the directory is otherwise protected.
+type FileTokenStore struct {
+	dir string
+}
+
+// NewFileTokenStore returns a FileTokenStore rooted at dir, creating the
+// directory with mode 0700 if it does not exist.
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if dir == "" {
+		return nil, errors.New("token store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create token store directory: %w", err)
+	}
+	return &FileTokenStore{dir: dir}, nil
+}
+
+// path returns the file path for key with the given extension. Keys are
+// hashed so arbitrary App IDs and scopes map to safe file names.
+func (s *FileTokenStore) path(key TokenKey, ext string) string {
+	sum := sha256.Sum256([]byte(key.String()))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+ext)
+}
+
+// Get implements TokenStore.
+func (s *FileTokenStore) Get(_ context.Context, key TokenKey) (*oauth2.Token, error) {
+	b, err := os.ReadFile(s.path(key, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var t oauth2.Token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode stored token: %w", err)
+	}
+	return &t, nil
+}
+
+// Put implements TokenStore.
+func (s *FileTokenStore) Put(_ context.Context, key TokenKey, tok *oauth2.Token) error {
+	if tok == nil {
+		return errors.New("token must not be nil")
+	}
+	b, err := json.Marshal(tok)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %w", err)
+	}
+	return atomicfile.WriteFile(s.path(key, ".json"), b, 0o600)
+}
+
+// Delete implements TokenStore.
+func (s *FileTokenStore) Delete(_ context.Context, key TokenKey) error {
+	err := os.Remove(s.path(key, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Lock implements TokenStore.
+func (s *FileTokenStore) Lock(ctx context.Context, key TokenKey, lease time.Duration) (func(), error) {
+	path := s.path(key, ".lock")
+	for {
+		release, err := tryLockFile(path, lease)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			var once sync.Once
+			unlock := func() { once.Do(release) }
+			timer := time.AfterFunc(lease, unlock)
+			return func() {
+				timer.Stop()
+				unlock()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
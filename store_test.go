@@ -0,0 +1,345 @@
+package githubauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestTokenStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) TokenStore{
+		"memory": func(*testing.T) TokenStore { return NewMemoryTokenStore() },
+		"file": func(t *testing.T) TokenStore {
+			s, err := NewFileTokenStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			key := TokenKey{App: "Iv1.abc", InstallationID: 7, Scope: "https://api.github.com/"}
+
+			if _, err := store.Get(ctx, key); !errors.Is(err, ErrTokenNotFound) {
+				t.Fatalf("Get(empty) err = %v, want ErrTokenNotFound", err)
+			}
+
+			want := &oauth2.Token{AccessToken: "ghs_one", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour).Round(0)}
+			if err := store.Put(ctx, key, want); err != nil {
+				t.Fatalf("Put() err = %v", err)
+			}
+			got, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get() err = %v", err)
+			}
+			if got.AccessToken != want.AccessToken || !got.Expiry.Equal(want.Expiry) {
+				t.Errorf("Get() = %+v, want %+v", got, want)
+			}
+
+			other := key
+			other.InstallationID = 8
+			if _, err := store.Get(ctx, other); !errors.Is(err, ErrTokenNotFound) {
+				t.Errorf("Get(other key) err = %v, want ErrTokenNotFound", err)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete() err = %v", err)
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete(missing) err = %v", err)
+			}
+			if _, err := store.Get(ctx, key); !errors.Is(err, ErrTokenNotFound) {
+				t.Errorf("Get(deleted) err = %v, want ErrTokenNotFound", err)
+			}
+
+			// Lock is exclusive until unlock.
+			unlock, err := store.Lock(ctx, key, time.Minute)
+			if err != nil {
+				t.Fatalf("Lock() err = %v", err)
+			}
+			short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			if _, err := store.Lock(short, key, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("second Lock() err = %v, want DeadlineExceeded", err)
+			}
+			cancel()
+			unlock()
+			unlock2, err := store.Lock(ctx, key, time.Minute)
+			if err != nil {
+				t.Fatalf("Lock() after unlock err = %v", err)
+			}
+			unlock2()
+
+			// An expired lease frees the lock without unlock being called.
+			if _, err := store.Lock(ctx, key, 50*time.Millisecond); err != nil {
+				t.Fatalf("Lock() err = %v", err)
+			}
+			waited, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			unlock3, err := store.Lock(waited, key, time.Minute)
+			if err != nil {
+				t.Fatalf("Lock() after lease expiry err = %v", err)
+			}
+			unlock3()
+		})
+	}
+}
+
+func TestFileTokenStore_FilePermissions(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := TokenKey{App: "1", InstallationID: 2}
+	if err := store.Put(context.Background(), key, &oauth2.Token{AccessToken: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(store.path(key, ".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", perm)
+	}
+
+	if _, err := NewFileTokenStore(""); err == nil {
+		t.Error("NewFileTokenStore(\"\") err = nil, want error")
+	}
+}
+
+func TestReuseTokenSourceWithSkew_TokenStore(t *testing.T) {
+	dir := t.TempDir()
+	key := TokenKey{App: "Iv1.abc", InstallationID: 7}
+
+	// Several wrappers, each with its own FileTokenStore handle on the same
+	// directory, stand in for cooperating processes sharing a volume.
+	var mints atomic.Int32
+	upstream := &countingSource{
+		delay: 20 * time.Millisecond,
+		mkToken: func(call int) *oauth2.Token {
+			mints.Add(1)
+			return &oauth2.Token{
+				AccessToken: fmt.Sprintf("token-%d", call),
+				TokenType:   "Bearer",
+				Expiry:      time.Now().Add(time.Hour),
+			}
+		},
+	}
+
+	const processes = 8
+	var wg sync.WaitGroup
+	got := make([]string, processes)
+	for p := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, err := NewFileTokenStore(dir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ts := ReuseTokenSourceWithSkew(nil, upstream, DefaultExpirySkew, WithTokenStore(store, key))
+			tok, err := ts.Token()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			got[p] = tok.AccessToken
+		}()
+	}
+	wg.Wait()
+
+	if n := mints.Load(); n != 1 {
+		t.Errorf("upstream mints = %d, want 1", n)
+	}
+	for p, tok := range got {
+		if tok != "token-1" {
+			t.Errorf("process %d got %q, want token-1", p, tok)
+		}
+	}
+}
+
+func TestReuseTokenSourceWithSkew_TokenStoreStaleEntry(t *testing.T) {
+	store := NewMemoryTokenStore()
+	key := TokenKey{App: "1", InstallationID: 1}
+	_ = store.Put(context.Background(), key, &oauth2.Token{
+		AccessToken: "stale",
+		Expiry:      time.Now().Add(10 * time.Second),
+	})
+
+	src := newShortLivedSource(time.Hour)
+	ts := ReuseTokenSourceWithSkew(nil, src, DefaultExpirySkew, WithTokenStore(store, key))
+
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "token-1" {
+		t.Errorf("AccessToken = %q, want a fresh mint instead of the stored token inside the skew window", tok.AccessToken)
+	}
+	stored, _ := store.Get(context.Background(), key)
+	if stored.AccessToken != "token-1" {
+		t.Errorf("stored token = %q, want the fresh mint to be published", stored.AccessToken)
+	}
+}
+
+// lockingStore is a MemoryTokenStore announcing every Lock call.
+type lockingStore struct {
+	*MemoryTokenStore
+	locking chan struct{}
+}
+
+func (s *lockingStore) Lock(ctx context.Context, key TokenKey, lease time.Duration) (func(), error) {
+	s.locking <- struct{}{}
+	return s.MemoryTokenStore.Lock(ctx, key, lease)
+}
+
+func TestReuseTokenSourceWithSkew_TokenStoreLockWait(t *testing.T) {
+	ctx := context.Background()
+	store := &lockingStore{MemoryTokenStore: NewMemoryTokenStore(), locking: make(chan struct{}, 1)}
+	key := TokenKey{App: "1", InstallationID: 1}
+
+	// Another process holds the store lock while it mints.
+	unlock, err := store.MemoryTokenStore.Lock(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached token is inside the skew window but has not expired.
+	cached := &oauth2.Token{AccessToken: "cached", Expiry: time.Now().Add(10 * time.Second)}
+	ts := ReuseTokenSourceWithSkew(cached, newShortLivedSource(time.Hour), DefaultExpirySkew, WithTokenStore(store, key))
+
+	refreshed := make(chan *oauth2.Token)
+	go func() {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Error(err)
+		}
+		refreshed <- tok
+	}()
+	<-store.locking
+
+	// While the refresh waits for the store lock, the cached token is served.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if tok, err := ts.Token(); err != nil || tok.AccessToken != "cached" {
+			t.Errorf("Token() during refresh = %v, %v, want the cached token", tok, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Token() blocked on the store lock held by the refresh")
+	}
+
+	_ = store.Put(ctx, key, &oauth2.Token{AccessToken: "shared", Expiry: time.Now().Add(time.Hour)})
+	unlock()
+	if tok := <-refreshed; tok == nil || tok.AccessToken != "shared" {
+		t.Errorf("refreshed token = %v, want the token published by the lock holder", tok)
+	}
+}
+
+func TestReuseTokenSourceWithSkew_TokenStoreInvalidatedMint(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	key := TokenKey{App: "1", InstallationID: 1}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	upstream := &countingSource{
+		mkToken: func(call int) *oauth2.Token {
+			if call == 1 {
+				close(started)
+				<-release
+			}
+			return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", call), Expiry: time.Now().Add(time.Hour)}
+		},
+	}
+	r := newReuseTokenSourceWithSkew(nil, upstream, DefaultExpirySkew, WithTokenStore(store, key))
+
+	minted := make(chan *oauth2.Token)
+	go func() {
+		tok, err := r.Token()
+		if err != nil {
+			t.Error(err)
+		}
+		minted <- tok
+	}()
+	<-started
+	r.invalidate()
+	close(release)
+
+	if tok := <-minted; tok == nil || tok.AccessToken != "token-1" {
+		t.Errorf("Token() = %v, want token-1 for the caller that minted it", tok)
+	}
+	if tok, err := store.Get(ctx, key); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("store.Get() = %v, %v, want a token minted across an invalidation not published", tok, err)
+	}
+	if tok, _ := r.current(); tok != nil {
+		t.Errorf("cached token = %v, want none after the invalidation", tok)
+	}
+}
+
+func TestWithInstallationTokenStore(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appSrc, err := NewApplicationTokenSource("Iv1.abc", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mints atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := mints.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(InstallationToken{
+			Token:     fmt.Sprintf("ghs_%d", n),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	store := NewMemoryTokenStore()
+	first := NewInstallationTokenSource(7, appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store))
+	second := NewInstallationTokenSource(7, appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store))
+	scoped := NewInstallationTokenSource(7, appSrc, WithBaseURL(server.URL), WithInstallationTokenStore(store),
+		WithInstallationTokenOptions(&InstallationTokenOptions{Repositories: []string{"octo-repo"}}))
+
+	for _, ts := range []oauth2.TokenSource{first, second} {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != "ghs_1" {
+			t.Errorf("AccessToken = %q, want shared ghs_1", tok.AccessToken)
+		}
+	}
+
+	tok, err := scoped.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "ghs_2" {
+		t.Errorf("scoped AccessToken = %q, want its own mint ghs_2", tok.AccessToken)
+	}
+
+	key := first.(*reuseTokenSourceWithSkew).key
+	if key.App != "Iv1.abc" || key.InstallationID != 7 {
+		t.Errorf("store key = %+v, want App and installation populated", key)
+	}
+}
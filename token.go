@@ -0,0 +1,147 @@
+package githubauth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jferrl/go-githubauth/internal/tokenformat"
+)
+
+// ErrMalformedToken is returned by ParseToken for strings that are not
+// well-formed GitHub tokens. Callers can branch with errors.Is.
+var ErrMalformedToken = errors.New("malformed GitHub token")
+
+// ErrNotPersonalAccessToken is returned by
+// NewValidatedPersonalAccessTokenSource for well-formed tokens of another
+// kind, such as installation (ghs_) or OAuth (gho_) tokens.
+var ErrNotPersonalAccessToken = errors.New("not a personal access token")
+
+// TokenKind identifies the type of a GitHub token by its prefix.
+type TokenKind int
+
+const (
+	// TokenKindUnknown is returned alongside an error by ParseToken.
+	TokenKindUnknown TokenKind = iota
+	// TokenKindPersonalAccess is a classic personal access token (ghp_).
+	TokenKindPersonalAccess
+	// TokenKindFineGrainedPersonalAccess is a fine-grained personal access
+	// token (github_pat_).
+	TokenKindFineGrainedPersonalAccess
+	// TokenKindOAuth is an OAuth App access token (gho_).
+	TokenKindOAuth
+	// TokenKindUserToServer is a GitHub App user access token (ghu_).
+	TokenKindUserToServer
+	// TokenKindInstallation is a GitHub App installation access token (ghs_),
+	// as minted by NewInstallationTokenSource.
+	TokenKindInstallation
+	// TokenKindRefresh is a GitHub App user refresh token (ghr_).
+	TokenKindRefresh
+)
+
+// String returns the token prefix of the kind, such as "ghp_", "jwt" for
+// TokenKindApplicationJWT, or "unknown".
+func (k TokenKind) String() string {
+	switch k {
+	case TokenKindPersonalAccess:
+		return "ghp_"
+	case TokenKindFineGrainedPersonalAccess:
+		return fineGrainedTokenPrefix
+	case TokenKindOAuth:
+		return "gho_"
+	case TokenKindUserToServer:
+		return "ghu_"
+	case TokenKindInstallation:
+		return "ghs_"
+	case TokenKindRefresh:
+		return "ghr_"
+	case TokenKindApplicationJWT:
+		return "jwt"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// Checksummed tokens are a 4-character prefix followed by 30 random
+	// base62 characters and the base62 CRC32 of those 30, zero-padded to 6.
+	tokenEntropyLength  = tokenformat.EntropyLength
+	tokenChecksumLength = tokenformat.ChecksumLength
+
+	// Fine-grained personal access tokens are github_pat_, a 22-character
+	// identifier, an underscore and a 59-character secret. Their checksum
+	// is not documented, so only the structure is checked.
+	fineGrainedTokenPrefix  = "github_pat_"
+	fineGrainedIDLength     = 22
+	fineGrainedSecretLength = 59
+)
+
+// checksummedTokenKinds maps the prefixes of checksummed tokens to their
+// kinds.
+var checksummedTokenKinds = map[string]TokenKind{
+	"ghp_": TokenKindPersonalAccess,
+	"gho_": TokenKindOAuth,
+	"ghu_": TokenKindUserToServer,
+	"ghs_": TokenKindInstallation,
+	"ghr_": TokenKindRefresh,
+}
+
+// ParseToken identifies the kind of a GitHub token from its prefix and checks
+// its format offline, so truncated, mistyped or placeholder tokens can be
+// rejected without a round trip to GitHub. For ghp_, gho_, ghu_, ghs_ and
+// ghr_ tokens the embedded CRC32 checksum is verified; for github_pat_
+// tokens only the structure is. Legacy 40-character hexadecimal tokens
+// without a prefix are reported as malformed.
+//
+// A token that passes ParseToken is not necessarily valid: it may have been
+// revoked or may never have been issued.
+//
+// Errors wrap ErrMalformedToken.
+//
+// See https://github.blog/engineering/platform-security/behind-githubs-new-authentication-token-formats/
+func ParseToken(token string) (TokenKind, error) {
+	if rest, ok := strings.CutPrefix(token, fineGrainedTokenPrefix); ok {
+		id, secret, ok := strings.Cut(rest, "_")
+		if !ok || len(id) != fineGrainedIDLength || len(secret) != fineGrainedSecretLength ||
+			!isBase62(id) || !isBase62(secret) {
+			return TokenKindUnknown, fmt.Errorf("%w: %s token does not match github_pat_<%d>_<%d>",
+				ErrMalformedToken, fineGrainedTokenPrefix, fineGrainedIDLength, fineGrainedSecretLength)
+		}
+		return TokenKindFineGrainedPersonalAccess, nil
+	}
+
+	if len(token) < 4 {
+		return TokenKindUnknown, fmt.Errorf("%w: unrecognized prefix", ErrMalformedToken)
+	}
+	kind, ok := checksummedTokenKinds[token[:4]]
+	if !ok {
+		return TokenKindUnknown, fmt.Errorf("%w: unrecognized prefix", ErrMalformedToken)
+	}
+
+	body := token[4:]
+	if len(body) != tokenEntropyLength+tokenChecksumLength || !isBase62(body) {
+		return TokenKindUnknown, fmt.Errorf("%w: %s token must be followed by %d base62 characters",
+			ErrMalformedToken, kind, tokenEntropyLength+tokenChecksumLength)
+	}
+	entropy, checksum := body[:tokenEntropyLength], body[tokenEntropyLength:]
+	if tokenChecksum(entropy) != checksum {
+		return TokenKindUnknown, fmt.Errorf("%w: %s token checksum mismatch", ErrMalformedToken, kind)
+	}
+	return kind, nil
+}
+
+// tokenChecksum returns the base62 CRC32 of entropy, zero-padded to
+// tokenChecksumLength.
+func tokenChecksum(entropy string) string {
+	return tokenformat.Checksum(entropy)
+}
+
+func isBase62(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package githubauth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseToken(t *testing.T) {
+	const entropy = "aBcDeFgHiJkLmNoPqRsTuVwXyZ0123"
+	checksum := tokenChecksum(entropy)
+	fineGrained := "github_pat_" + strings.Repeat("A", 22) + "_" + strings.Repeat("b", 59)
+
+	tests := []struct {
+		name    string
+		token   string
+		want    TokenKind
+		wantErr bool
+	}{
+		{name: "classic personal access token", token: "ghp_" + entropy + checksum, want: TokenKindPersonalAccess},
+		{name: "OAuth token", token: "gho_" + entropy + checksum, want: TokenKindOAuth},
+		{name: "user-to-server token", token: "ghu_" + entropy + checksum, want: TokenKindUserToServer},
+		{name: "installation token", token: "ghs_" + entropy + checksum, want: TokenKindInstallation},
+		{name: "refresh token", token: "ghr_" + entropy + checksum, want: TokenKindRefresh},
+		{name: "fine-grained personal access token", token: fineGrained, want: TokenKindFineGrainedPersonalAccess},
+		{name: "checksum mismatch", token: "ghp_" + entropy + "000000", wantErr: true},
+		{name: "entropy altered", token: "ghp_b" + entropy[1:] + checksum, wantErr: true},
+		{name: "truncated", token: "ghp_" + entropy + checksum[:5], wantErr: true},
+		{name: "non-base62 character", token: "ghp_" + entropy[:29] + "-" + checksum, wantErr: true},
+		{name: "unknown prefix", token: "ghx_" + entropy + checksum, wantErr: true},
+		{name: "legacy hexadecimal token", token: strings.Repeat("a1", 20), wantErr: true},
+		{name: "fine-grained without separator", token: "github_pat_" + strings.Repeat("A", 82), wantErr: true},
+		{name: "fine-grained truncated", token: fineGrained[:len(fineGrained)-1], wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToken(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMalformedToken) || got != TokenKindUnknown {
+					t.Fatalf("ParseToken() = %v, %v, want ErrMalformedToken", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseToken() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenChecksum(t *testing.T) {
+	// The CRC32 of the empty string is 0, which must be zero-padded.
+	if got := tokenChecksum(""); got != "000000" {
+		t.Errorf("tokenChecksum(\"\") = %q, want 000000", got)
+	}
+	// CRC32("123456789") = 0xCBF43926 = 3421780262, base62 "3jZRME".
+	if got := tokenChecksum("123456789"); got != "3jZRME" {
+		t.Errorf("tokenChecksum(123456789) = %q, want 3jZRME", got)
+	}
+}
+
+func TestNewValidatedPersonalAccessTokenSource(t *testing.T) {
+	entropy := "aBcDeFgHiJkLmNoPqRsTuVwXyZ0123"
+	classic := "ghp_" + entropy + tokenChecksum(entropy)
+	fineGrained := "github_pat_" + strings.Repeat("A", 22) + "_" + strings.Repeat("b", 59)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "classic", token: classic},
+		{name: "fine-grained", token: fineGrained},
+		{name: "placeholder", token: "ghp_placeholder", wantErr: ErrMalformedToken},
+		{name: "empty", token: "", wantErr: ErrMalformedToken},
+		{name: "installation", token: "ghs_" + entropy + tokenChecksum(entropy), wantErr: ErrNotPersonalAccessToken},
+		{name: "refresh", token: "ghr_" + entropy + tokenChecksum(entropy), wantErr: ErrNotPersonalAccessToken},
+		{name: "user-to-server", token: "ghu_" + entropy + tokenChecksum(entropy), wantErr: ErrNotPersonalAccessToken},
+		{name: "oauth", token: "gho_" + entropy + tokenChecksum(entropy), wantErr: ErrNotPersonalAccessToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := NewValidatedPersonalAccessTokenSource(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || src != nil {
+					t.Fatalf("NewValidatedPersonalAccessTokenSource() = %v, %v, want %v", src, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok, err := src.Token(); err != nil || tok.AccessToken != tt.token {
+				t.Errorf("Token() = %v, %v, want %s", tok, err, tt.token)
+			}
+		})
+	}
+
+	// The plain constructor passes the token through unchecked.
+	if _, err := NewPersonalAccessTokenSource("ghp_placeholder").Token(); err != nil {
+		t.Errorf("Token() without validation err = %v", err)
+	}
+}
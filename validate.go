@@ -0,0 +1,138 @@
+package githubauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// maxClockOffset is the clock offset beyond which GitHub starts rejecting App
+// JWTs: iat is backdated 60 seconds, so a larger drift puts it in the future
+// (or exp in the past) from GitHub's point of view.
+const maxClockOffset = 60 * time.Second
+
+// ValidationReport describes the GitHub App behind a set of credentials as
+// reported by GitHub, together with local diagnostics useful when a
+// deployment fails to authenticate.
+type ValidationReport struct {
+	// AppID, ClientID, Slug and Name identify the App the JWT authenticated as.
+	AppID    int64
+	ClientID string
+	Slug     string
+	Name     string
+	// Owner is the login of the user or organization that owns the App.
+	Owner string
+	// Permissions maps each permission granted to the App to its access
+	// level ("read" or "write").
+	Permissions map[string]string
+	// Events lists the webhook events the App is subscribed to.
+	Events []string
+	// ClockOffset is the difference between GitHub's clock (taken from the
+	// Date response header) and the local clock. A positive value means the
+	// local clock is behind. GitHub rejects App JWTs once the offset exceeds
+	// about 60 seconds. Zero when GitHub did not send a Date header.
+	ClockOffset time.Duration
+	// KeyFingerprint is the SHA-256 fingerprint of the App private key (see
+	// KeyFingerprint). It is empty when appSource was not created by
+	// NewApplicationTokenSource or NewApplicationTokenSourceFromSigner.
+	KeyFingerprint string
+
+	// InstallationID is the installation an access token was minted for, or
+	// zero when Validate was called without one.
+	InstallationID int64
+	// InstallationExpiresAt is the expiry of the minted installation token.
+	InstallationExpiresAt time.Time
+	// InstallationPermissions are the permissions granted to the minted
+	// installation token.
+	InstallationPermissions *InstallationPermissions
+}
+
+// Validate checks GitHub App credentials end to end so misconfiguration
+// surfaces at startup rather than on the first real request. It signs an App
+// JWT with appSource, calls GET /app to confirm GitHub accepts it and, when
+// installationID is non-zero, mints an installation access token for that
+// installation. The minted token is discarded.
+//
+// opts accepts the same options as NewInstallationTokenSource, so the target
+// API (WithEnterpriseURL, WithBaseURL), transport (WithHTTPClient) and
+// installation token scope (WithInstallationTokenOptions) match the
+// production configuration. WithContext is ignored in favor of ctx.
+//
+// On failure the returned error names the step that failed and the report
+// holds everything gathered up to that point, so both can be surfaced by a
+// readiness probe. A rejected JWT combined with a clock offset beyond 60
+// seconds is reported as clock skew.
+//
+// See https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#get-the-authenticated-app
+func Validate(ctx context.Context, appSource oauth2.TokenSource, installationID int64, opts ...InstallationTokenSourceOpt) (*ValidationReport, error) {
+	if appSource == nil {
+		return nil, errors.New("application token source is required")
+	}
+
+	i := newInstallationTokenSource(installationID, appSource, opts...)
+	if i.configErr != nil {
+		return nil, i.configErr
+	}
+
+	report := &ValidationReport{}
+	if app, ok := applicationSourceOf(appSource); ok {
+		fp, err := SignerFingerprint(app.signer)
+		if err != nil {
+			return report, fmt.Errorf("validate: %w", err)
+		}
+		report.KeyFingerprint = fp
+	}
+
+	// Sign up front so a broken key or unreachable KMS is reported as such
+	// rather than as a transport error from the GET /app request.
+	if _, err := appSource.Token(); err != nil {
+		return report, fmt.Errorf("validate: signing application JWT: %w", err)
+	}
+
+	start := i.clock.Now()
+	app, serverTime, err := i.client.getApp(ctx)
+	if !serverTime.IsZero() {
+		// Date has second precision; compare against the request midpoint.
+		local := start.Add(i.clock.Now().Sub(start) / 2)
+		report.ClockOffset = serverTime.Sub(local).Round(time.Second)
+	}
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			if report.ClockOffset > maxClockOffset || report.ClockOffset < -maxClockOffset {
+				return report, fmt.Errorf("validate: GitHub rejected the application JWT; local clock is off by %s: %w", report.ClockOffset, err)
+			}
+			return report, fmt.Errorf("validate: GitHub rejected the application JWT; check the App ID or Client ID, the private key, and the API URL: %w", err)
+		}
+		return report, fmt.Errorf("validate: fetching app: %w", err)
+	}
+
+	report.AppID = app.ID
+	report.ClientID = app.ClientID
+	report.Slug = app.Slug
+	report.Name = app.Name
+	if app.Owner != nil {
+		report.Owner = app.Owner.Login
+	}
+	report.Permissions = app.Permissions
+	report.Events = app.Events
+
+	if installationID == 0 {
+		return report, nil
+	}
+
+	token, err := i.client.createInstallationToken(ctx, installationID, i.opts)
+	if err != nil {
+		return report, fmt.Errorf("validate: creating installation token for installation %d: %w", installationID, err)
+	}
+
+	report.InstallationID = installationID
+	report.InstallationExpiresAt = token.ExpiresAt
+	report.InstallationPermissions = token.Permissions
+
+	return report, nil
+}
@@ -0,0 +1,175 @@
+package githubauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidate(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFingerprint, err := publicKeyFingerprint(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appHandler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("GET /app missing bearer token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(App{
+			ID:          42,
+			ClientID:    "Iv1.abc",
+			Slug:        "octo-app",
+			Name:        "Octo App",
+			Owner:       &Account{Login: "octo-org", Type: "Organization"},
+			Permissions: map[string]string{"contents": "read"},
+			Events:      []string{"push"},
+		})
+	}
+
+	tests := []struct {
+		name           string
+		installationID int64
+		handler        http.HandlerFunc
+		wantErr        string
+		check          func(t *testing.T, r *ValidationReport)
+	}{
+		{
+			name:    "app only",
+			handler: appHandler,
+			check: func(t *testing.T, r *ValidationReport) {
+				if r.Slug != "octo-app" || r.Owner != "octo-org" || r.AppID != 42 {
+					t.Errorf("report = %+v, want app details", r)
+				}
+				if r.Permissions["contents"] != "read" || len(r.Events) != 1 {
+					t.Errorf("permissions/events = %v/%v", r.Permissions, r.Events)
+				}
+				if r.KeyFingerprint != wantFingerprint {
+					t.Errorf("KeyFingerprint = %q, want %q", r.KeyFingerprint, wantFingerprint)
+				}
+				if r.InstallationID != 0 {
+					t.Errorf("InstallationID = %d, want 0", r.InstallationID)
+				}
+			},
+		},
+		{
+			name:           "app and installation",
+			installationID: 7,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/app/installations/7/access_tokens" {
+					w.WriteHeader(http.StatusCreated)
+					_ = json.NewEncoder(w).Encode(InstallationToken{
+						Token:       "ghs_test",
+						ExpiresAt:   time.Now().Add(time.Hour),
+						Permissions: &InstallationPermissions{Contents: Ptr("read")},
+					})
+					return
+				}
+				appHandler(w, r)
+			},
+			check: func(t *testing.T, r *ValidationReport) {
+				if r.InstallationID != 7 || r.InstallationExpiresAt.IsZero() {
+					t.Errorf("installation details missing: %+v", r)
+				}
+				if r.InstallationPermissions == nil || *r.InstallationPermissions.Contents != "read" {
+					t.Errorf("InstallationPermissions = %+v", r.InstallationPermissions)
+				}
+			},
+		},
+		{
+			name: "rejected JWT",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+			},
+			wantErr: "check the App ID",
+		},
+		{
+			name: "rejected JWT with clock skew",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Date", time.Now().Add(5*time.Minute).UTC().Format(http.TimeFormat))
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"'Issued at' claim ('iat') must be an Integer representing a time in the past"}`))
+			},
+			wantErr: "local clock is off",
+			check: func(t *testing.T, r *ValidationReport) {
+				if r.ClockOffset < 4*time.Minute {
+					t.Errorf("ClockOffset = %v, want ~5m", r.ClockOffset)
+				}
+			},
+		},
+		{
+			name:           "installation not found",
+			installationID: 9,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/app" {
+					appHandler(w, r)
+					return
+				}
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			},
+			wantErr: "installation 9",
+			check: func(t *testing.T, r *ValidationReport) {
+				if r.Slug != "octo-app" {
+					t.Errorf("report should keep app details gathered before the failure, got %+v", r)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			appSrc, err := NewApplicationTokenSource(int64(42), privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := Validate(context.Background(), appSrc, tt.installationID, WithBaseURL(server.URL))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() err = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() err = %v, want containing %q", err, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Errorf("err = %v, want wrapping *APIError", err)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, report)
+			}
+		})
+	}
+}
+
+func TestValidate_ConfigErrors(t *testing.T) {
+	if _, err := Validate(context.Background(), nil, 0); err == nil {
+		t.Error("Validate(nil source) err = nil, want error")
+	}
+
+	src := oauth2StaticSource{accessToken: "jwt"}
+	if _, err := Validate(context.Background(), src, 0, WithBaseURL("ht\ntp://invalid")); err == nil {
+		t.Error("Validate(invalid URL) err = nil, want error")
+	}
+}
@@ -0,0 +1,144 @@
+package githubauth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultApplicationJWTLeeway is the clock skew tolerated by
+// VerifyApplicationJWT when comparing iat and exp with the current time. It
+// matches the 60 seconds NewApplicationTokenSource backdates iat by.
+const DefaultApplicationJWTLeeway = 60 * time.Second
+
+// ErrInvalidApplicationJWT is returned by VerifyApplicationJWT and
+// DecodeApplicationJWT for tokens GitHub would not accept as App JWTs.
+// Callers can branch with errors.Is.
+var ErrInvalidApplicationJWT = errors.New("invalid application JWT")
+
+// ApplicationJWTClaims are the claims of a GitHub App JWT.
+type ApplicationJWTClaims struct {
+	// Issuer is the raw iss claim.
+	Issuer string
+	// AppID is set when the issuer is a numeric App ID.
+	AppID int64
+	// ClientID is set when the issuer is a Client ID.
+	ClientID string
+	// IssuedAt and ExpiresAt are the iat and exp claims.
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// VerifyApplicationJWTOpt is a functional option for VerifyApplicationJWT.
+type VerifyApplicationJWTOpt func(*applicationJWTVerifier)
+
+type applicationJWTVerifier struct {
+	leeway time.Duration
+	clock  Clock
+}
+
+// WithVerifyLeeway sets the clock skew tolerated when comparing iat and exp
+// with the current time. Defaults to DefaultApplicationJWTLeeway; negative
+// values are treated as zero.
+func WithVerifyLeeway(d time.Duration) VerifyApplicationJWTOpt {
+	return func(v *applicationJWTVerifier) {
+		v.leeway = max(d, 0)
+	}
+}
+
+// WithVerifyClock sets the Clock supplying the current time.
+func WithVerifyClock(c Clock) VerifyApplicationJWTOpt {
+	return func(v *applicationJWTVerifier) {
+		if c != nil {
+			v.clock = c
+		}
+	}
+}
+
+// VerifyApplicationJWT parses a GitHub App JWT, verifies its RS256 signature
+// against key (an *rsa.PublicKey) and checks its claims the way GitHub does:
+// iss must be set, iat must not be in the future, exp must not have passed,
+// and exp may be at most 10 minutes after iat.
+// Comparisons with the current time tolerate DefaultApplicationJWTLeeway of
+// clock skew; see WithVerifyLeeway.
+//
+// Errors wrap ErrInvalidApplicationJWT.
+func VerifyApplicationJWT(token string, key crypto.PublicKey, opts ...VerifyApplicationJWTOpt) (*ApplicationJWTClaims, error) {
+	v := &applicationJWTVerifier{
+		leeway: DefaultApplicationJWTLeeway,
+		clock:  defaultClock,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: public key must be RSA (GitHub requires RS256)", ErrInvalidApplicationJWT)
+	}
+
+	var registered jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &registered, func(*jwt.Token) (any, error) { return rsaKey, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.clock.Now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidApplicationJWT, err)
+	}
+
+	// iat is not in the future and exp is at most 10 minutes after it, so exp
+	// is also at most 10 minutes (plus leeway) from now.
+	return applicationJWTClaims(&registered)
+}
+
+// DecodeApplicationJWT returns the claims of a GitHub App JWT without
+// verifying its signature or comparing it with the current time, for
+// debugging what a token source produced. Only the structure is checked: iss,
+// iat and exp must be present and exp may be at most 10 minutes after iat.
+// Never trust the result for authentication; use VerifyApplicationJWT.
+//
+// Errors wrap ErrInvalidApplicationJWT.
+func DecodeApplicationJWT(token string) (*ApplicationJWTClaims, error) {
+	var registered jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &registered); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidApplicationJWT, err)
+	}
+	return applicationJWTClaims(&registered)
+}
+
+// applicationJWTClaims checks the structural GitHub requirements on c and
+// converts it.
+func applicationJWTClaims(c *jwt.RegisteredClaims) (*ApplicationJWTClaims, error) {
+	if c.Issuer == "" {
+		return nil, fmt.Errorf("%w: missing iss claim", ErrInvalidApplicationJWT)
+	}
+	if c.IssuedAt == nil || c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: missing iat or exp claim", ErrInvalidApplicationJWT)
+	}
+	lifetime := c.ExpiresAt.Sub(c.IssuedAt.Time)
+	// DefaultApplicationTokenExpiration is also the longest lifetime GitHub
+	// accepts.
+	if lifetime <= 0 || lifetime > DefaultApplicationTokenExpiration {
+		return nil, fmt.Errorf("%w: lifetime %s is not within (0, %s]", ErrInvalidApplicationJWT, lifetime, DefaultApplicationTokenExpiration)
+	}
+
+	claims := &ApplicationJWTClaims{
+		Issuer:    c.Issuer,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiresAt: c.ExpiresAt.Time,
+	}
+	if id, err := strconv.ParseInt(c.Issuer, 10, 64); err == nil {
+		claims.AppID = id
+	} else {
+		claims.ClientID = c.Issuer
+	}
+	return claims, nil
+}
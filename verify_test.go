@@ -0,0 +1,128 @@
+package githubauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyApplicationJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(now)
+
+	sign := func(claims jwt.RegisteredClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	claims := func(iss string, iat, exp time.Duration) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    iss,
+			IssuedAt:  jwt.NewNumericDate(now.Add(iat)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
+		}
+	}
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("1", 0, time.Minute)).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		key          any
+		opts         []VerifyApplicationJWTOpt
+		wantAppID    int64
+		wantClientID string
+	}{
+		{name: "app ID issuer", token: sign(claims("123", -time.Minute, 9*time.Minute)), key: &key.PublicKey, wantAppID: 123},
+		{name: "client ID issuer", token: sign(claims("Iv1.abc", -time.Minute, 9*time.Minute)), key: &key.PublicKey, wantClientID: "Iv1.abc"},
+		{name: "expired within leeway", token: sign(claims("1", -5*time.Minute, -30*time.Second)), key: &key.PublicKey, wantAppID: 1},
+		{name: "expired beyond leeway", token: sign(claims("1", -5*time.Minute, -2*time.Minute)), key: &key.PublicKey},
+		{name: "expired without leeway", token: sign(claims("1", -5*time.Minute, -30*time.Second)), key: &key.PublicKey, opts: []VerifyApplicationJWTOpt{WithVerifyLeeway(0)}},
+		{name: "issued in the future", token: sign(claims("1", 2*time.Minute, 5*time.Minute)), key: &key.PublicKey},
+		{name: "lifetime over 10 minutes", token: sign(claims("1", -time.Minute, 10*time.Minute)), key: &key.PublicKey},
+		{name: "missing issuer", token: sign(claims("", -time.Minute, 9*time.Minute)), key: &key.PublicKey},
+		{name: "wrong key", token: sign(claims("1", -time.Minute, 9*time.Minute)), key: &otherKey.PublicKey},
+		{name: "HS256", token: hs256, key: &key.PublicKey},
+		{name: "non-RSA key", token: sign(claims("1", -time.Minute, 9*time.Minute)), key: &ecKey.PublicKey},
+		{name: "garbage", token: "not.a.jwt", key: &key.PublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]VerifyApplicationJWTOpt{WithVerifyClock(clock)}, tt.opts...)
+			got, err := VerifyApplicationJWT(tt.token, tt.key, opts...)
+
+			wantErr := tt.wantAppID == 0 && tt.wantClientID == ""
+			if wantErr {
+				if !errors.Is(err, ErrInvalidApplicationJWT) {
+					t.Fatalf("VerifyApplicationJWT() err = %v, want ErrInvalidApplicationJWT", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyApplicationJWT() err = %v", err)
+			}
+			if got.AppID != tt.wantAppID || got.ClientID != tt.wantClientID {
+				t.Errorf("VerifyApplicationJWT() = %+v, want app ID %d client ID %q", got, tt.wantAppID, tt.wantClientID)
+			}
+		})
+	}
+}
+
+func TestVerifyApplicationJWT_RoundTrip(t *testing.T) {
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := NewApplicationTokenSource("Iv1.roundtrip", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := VerifyApplicationJWT(tok.AccessToken, &rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("VerifyApplicationJWT() err = %v", err)
+	}
+	if got.ClientID != "Iv1.roundtrip" || !got.ExpiresAt.Equal(tok.Expiry.Truncate(time.Second)) {
+		t.Errorf("VerifyApplicationJWT() = %+v, want client ID Iv1.roundtrip expiring at %v", got, tok.Expiry)
+	}
+
+	decoded, err := DecodeApplicationJWT(tok.AccessToken)
+	if err != nil || *decoded != *got {
+		t.Errorf("DecodeApplicationJWT() = %+v, %v, want %+v", decoded, err, got)
+	}
+	if _, err := DecodeApplicationJWT("ghs_notajwt"); !errors.Is(err, ErrInvalidApplicationJWT) {
+		t.Errorf("DecodeApplicationJWT(ghs_) err = %v, want ErrInvalidApplicationJWT", err)
+	}
+}
@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DeliveryState is the processing state of a delivery in a DeliveryStore.
+type DeliveryState int
+
+const (
+	// DeliveryNew reports a delivery seen for the first time (or whose
+	// earlier claim was released or expired); the caller now holds the
+	// claim.
+	DeliveryNew DeliveryState = iota
+	// DeliveryInProgress reports a delivery claimed by another request that
+	// has not completed yet.
+	DeliveryInProgress
+	// DeliveryCompleted reports a delivery already processed successfully.
+	DeliveryCompleted
+)
+
+// String returns the lowercase name of the state.
+func (s DeliveryState) String() string {
+	switch s {
+	case DeliveryNew:
+		return "new"
+	case DeliveryInProgress:
+		return "in_progress"
+	case DeliveryCompleted:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
+
+// Defaults used by Deduplicate and the delivery stores.
+const (
+	// DefaultClaimLease is how long a claim blocks redeliveries before it
+	// is considered abandoned, e.g. because the process crashed.
+	DefaultClaimLease = 5 * time.Minute
+	// DefaultDeliveryRetention is how long completed deliveries are
+	// remembered. GitHub lets deliveries of the past 3 days be redelivered.
+	DefaultDeliveryRetention = 72 * time.Hour
+)
+
+// DeliveryStore records which deliveries are being or have been processed,
+// keyed on the X-GitHub-Delivery GUID, which GitHub keeps on redelivery.
+// Implementations must be safe for concurrent use.
+type DeliveryStore interface {
+	// Claim marks delivery id as in progress for at most lease, unless it
+	// already is or has completed. It returns DeliveryNew when the claim was
+	// taken, and the current state otherwise.
+	Claim(ctx context.Context, id string, lease time.Duration) (DeliveryState, error)
+	// Complete marks a claimed delivery as processed.
+	Complete(ctx context.Context, id string) error
+	// Release drops a claim so a redelivery is processed again. Releasing
+	// an unknown delivery is not an error.
+	Release(ctx context.Context, id string) error
+}
+
+// DeduplicateOpt is a functional option for Deduplicate.
+type DeduplicateOpt func(*dedupConfig)
+
+type dedupConfig struct {
+	lease   time.Duration
+	onError func(*http.Request, error)
+}
+
+// WithClaimLease sets how long a delivery stays claimed while its handler
+// runs. It should exceed the slowest handler; a claim is dropped earlier
+// when the handler returns. Defaults to DefaultClaimLease.
+func WithClaimLease(d time.Duration) DeduplicateOpt {
+	return func(c *dedupConfig) {
+		if d > 0 {
+			c.lease = d
+		}
+	}
+}
+
+// WithDeduplicateErrorHandler sets a function called when a handled delivery
+// cannot be recorded as completed or its claim cannot be released. By
+// default these failures are logged with slog.Default.
+func WithDeduplicateErrorHandler(fn func(r *http.Request, err error)) DeduplicateOpt {
+	return func(c *dedupConfig) {
+		if fn != nil {
+			c.onError = fn
+		}
+	}
+}
+
+// Deduplicate returns net/http middleware that processes each delivery once.
+// Mount it behind Middleware, so only verified deliveries are recorded:
+//
+//	store := webhook.NewMemoryDeliveryStore(0)
+//	handler := webhook.Middleware(secret)(webhook.Deduplicate(store)(router))
+//
+// The first request for a delivery GUID claims it and runs next. A 2xx
+// response, including the implicit 200 of a handler that writes no header,
+// marks the delivery completed; any other status, or a panic, releases the
+// claim so GitHub's redelivery is processed again. If the store fails to
+// record the completion, the claim is kept until its lease expires rather
+// than released, and the error is reported to the handler set with
+// WithDeduplicateErrorHandler. Duplicates
+// of a completed delivery get 200 OK without reaching next, and duplicates
+// arriving while the delivery is still being processed get 409 Conflict.
+//
+// Requests without an X-GitHub-Delivery header are passed to next
+// unchanged. Store failures are reported with 500 Internal Server Error.
+func Deduplicate(store DeliveryStore, opts ...DeduplicateOpt) func(http.Handler) http.Handler {
+	cfg := dedupConfig{lease: DefaultClaimLease, onError: logDedupError}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(DeliveryHeader)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			state, err := store.Claim(r.Context(), id, cfg.lease)
+			if err != nil {
+				http.Error(w, "failed to claim delivery", http.StatusInternalServerError)
+				return
+			}
+			switch state {
+			case DeliveryCompleted:
+				http.Error(w, "delivery already processed", http.StatusOK)
+				return
+			case DeliveryInProgress:
+				http.Error(w, "delivery is being processed", http.StatusConflict)
+				return
+			}
+
+			// The claim outlives a canceled request context.
+			ctx := context.WithoutCancel(r.Context())
+			sw := &statusWriter{ResponseWriter: w}
+			handled := false
+			defer func() {
+				if handled {
+					return
+				}
+				if err := store.Release(ctx, id); err != nil {
+					cfg.onError(r, fmt.Errorf("failed to release delivery %s: %w", id, err))
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+
+			// A handler that writes nothing responds 200 OK.
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= 200 && status < 300 {
+				handled = true
+				if err := store.Complete(ctx, id); err != nil {
+					cfg.onError(r, fmt.Errorf("failed to complete delivery %s: %w", id, err))
+				}
+			}
+		})
+	}
+}
+
+// logDedupError is the default Deduplicate error handler.
+func logDedupError(r *http.Request, err error) {
+	slog.Default().ErrorContext(r.Context(), "webhook: deduplication store failure", slog.Any("error", err))
+}
+
+// statusWriter records the status code written by a handler. It stays 0
+// when the handler writes nothing.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// sweepInterval bounds how often MemoryDeliveryStore scans for expired
+// entries.
+const sweepInterval = time.Minute
+
+// MemoryDeliveryStore is an in-process DeliveryStore. Deliveries are
+// forgotten when the process exits, so use FileDeliveryStore or a shared
+// implementation to deduplicate across restarts and replicas. The zero value
+// is not usable; create one with NewMemoryDeliveryStore.
+type MemoryDeliveryStore struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]deliveryEntry
+	lastSweep time.Time
+}
+
+type deliveryEntry struct {
+	state   DeliveryState
+	expires time.Time
+}
+
+// NewMemoryDeliveryStore returns an empty MemoryDeliveryStore remembering
+// completed deliveries for retention, or DefaultDeliveryRetention when
+// retention is not positive.
+func NewMemoryDeliveryStore(retention time.Duration) *MemoryDeliveryStore {
+	if retention <= 0 {
+		retention = DefaultDeliveryRetention
+	}
+	return &MemoryDeliveryStore{
+		retention: retention,
+		now:       time.Now,
+		entries:   make(map[string]deliveryEntry),
+	}
+}
+
+// Claim implements DeliveryStore.
+func (s *MemoryDeliveryStore) Claim(_ context.Context, id string, lease time.Duration) (DeliveryState, error) {
+	if id == "" {
+		return 0, errInvalidDeliveryID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[id]; ok && now.Before(e.expires) {
+		return e.state, nil
+	}
+	s.entries[id] = deliveryEntry{state: DeliveryInProgress, expires: now.Add(lease)}
+	return DeliveryNew, nil
+}
+
+// Complete implements DeliveryStore.
+func (s *MemoryDeliveryStore) Complete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = deliveryEntry{state: DeliveryCompleted, expires: s.now().Add(s.retention)}
+	return nil
+}
+
+// Release implements DeliveryStore.
+func (s *MemoryDeliveryStore) Release(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[id]; ok && e.state == DeliveryInProgress {
+		delete(s.entries, id)
+	}
+	return nil
+}
+
+// errInvalidDeliveryID is returned by stores for empty delivery IDs.
+var errInvalidDeliveryID = errors.New("webhook: delivery ID is required")
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jferrl/go-githubauth/internal/atomicfile"
+)
+
+// FileDeliveryStore is a DeliveryStore backed by a directory, so deliveries
+// are deduplicated across restarts and by every process sharing the
+// directory. Each delivery is a small file; claims are taken by exclusive
+// file creation, so two processes cannot both claim a new delivery. Taking
+// over an expired claim is best effort across processes.
+//
+// Expired entries are removed when the same delivery is claimed again; call
+// Prune periodically to bound the size of the directory.
+type FileDeliveryStore struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+}
+
+// deliveryRecord is the content of a FileDeliveryStore file.
+type deliveryRecord struct {
+	State   string    `json:"state"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewFileDeliveryStore returns a FileDeliveryStore rooted at dir, creating
+// the directory with mode 0700 if it does not exist. Completed deliveries are
+// remembered for retention, or DefaultDeliveryRetention when retention is not
+// positive.
+func NewFileDeliveryStore(dir string, retention time.Duration) (*FileDeliveryStore, error) {
+	if dir == "" {
+		return nil, errors.New("delivery store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create delivery store directory: %w", err)
+	}
+	if retention <= 0 {
+		retention = DefaultDeliveryRetention
+	}
+	return &FileDeliveryStore{dir: dir, retention: retention, now: time.Now}, nil
+}
+
+// path returns the file path for delivery id. IDs are hashed so arbitrary
+// header values map to safe file names.
+func (s *FileDeliveryStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Claim implements DeliveryStore.
+func (s *FileDeliveryStore) Claim(_ context.Context, id string, lease time.Duration) (DeliveryState, error) {
+	if id == "" {
+		return 0, errInvalidDeliveryID
+	}
+	path := s.path(id)
+	claim, err := json.Marshal(deliveryRecord{State: DeliveryInProgress.String(), Expires: s.now().Add(lease)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode delivery claim: %w", err)
+	}
+
+	// One retry: an expired entry is removed and claimed afresh.
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, werr := f.Write(claim)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				_ = os.Remove(path)
+				return 0, fmt.Errorf("failed to write delivery claim: %w", werr)
+			}
+			return DeliveryNew, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return 0, fmt.Errorf("failed to claim delivery: %w", err)
+		}
+
+		rec, err := readDeliveryRecord(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // released concurrently
+		}
+		if err != nil {
+			return 0, err
+		}
+		if s.now().Before(rec.Expires) {
+			if rec.State == DeliveryCompleted.String() {
+				return DeliveryCompleted, nil
+			}
+			return DeliveryInProgress, nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, fmt.Errorf("failed to remove expired delivery: %w", err)
+		}
+	}
+	// Another process took the delivery between our attempts.
+	return DeliveryInProgress, nil
+}
+
+// Complete implements DeliveryStore.
+func (s *FileDeliveryStore) Complete(_ context.Context, id string) error {
+	if id == "" {
+		return errInvalidDeliveryID
+	}
+	b, err := json.Marshal(deliveryRecord{State: DeliveryCompleted.String(), Expires: s.now().Add(s.retention)})
+	if err != nil {
+		return fmt.Errorf("failed to encode delivery record: %w", err)
+	}
+	return atomicfile.WriteFile(s.path(id), b, 0o600)
+}
+
+// Release implements DeliveryStore.
+func (s *FileDeliveryStore) Release(_ context.Context, id string) error {
+	if id == "" {
+		return errInvalidDeliveryID
+	}
+	path := s.path(id)
+	rec, err := readDeliveryRecord(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if rec.State == DeliveryCompleted.String() {
+		return nil
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Prune removes expired entries and returns how many were removed.
+func (s *FileDeliveryStore) Prune(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list delivery store: %w", err)
+	}
+	now := s.now()
+	removed := 0
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		rec, err := readDeliveryRecord(path)
+		if err != nil || now.Before(rec.Expires) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func readDeliveryRecord(path string) (deliveryRecord, error) {
+	var rec deliveryRecord
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		// A claim is written right after its file is created, so an
+		// undecodable file is a claim being written, or one abandoned
+		// mid-write. Treat it as in progress for a lease from its creation.
+		info, err := os.Stat(path)
+		if err != nil {
+			return rec, err
+		}
+		return deliveryRecord{State: DeliveryInProgress.String(), Expires: info.ModTime().Add(DefaultClaimLease)}, nil
+	}
+	return rec, nil
+}
@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func newDeliveryStores(t *testing.T, now func() time.Time) map[string]DeliveryStore {
+	t.Helper()
+	mem := NewMemoryDeliveryStore(time.Hour)
+	mem.now = now
+	file, err := NewFileDeliveryStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.now = now
+	return map[string]DeliveryStore{"memory": mem, "file": file}
+}
+
+func TestDeliveryStores(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	for name, store := range newDeliveryStores(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			claim := func(id string, want DeliveryState) {
+				t.Helper()
+				got, err := store.Claim(ctx, id, time.Minute)
+				if err != nil || got != want {
+					t.Fatalf("Claim(%s) = %v, %v, want %v", id, got, err, want)
+				}
+			}
+
+			claim(name+"-a", DeliveryNew)
+			claim(name+"-a", DeliveryInProgress)
+
+			// A released claim can be taken again.
+			if err := store.Release(ctx, name+"-a"); err != nil {
+				t.Fatal(err)
+			}
+			claim(name+"-a", DeliveryNew)
+
+			// A completed delivery stays completed; Release does not undo it.
+			if err := store.Complete(ctx, name+"-a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Release(ctx, name+"-a"); err != nil {
+				t.Fatal(err)
+			}
+			claim(name+"-a", DeliveryCompleted)
+
+			// An abandoned claim expires after its lease.
+			claim(name+"-b", DeliveryNew)
+			now = now.Add(2 * time.Minute)
+			claim(name+"-b", DeliveryNew)
+
+			// Completed deliveries are forgotten after the retention.
+			claim(name+"-a", DeliveryCompleted)
+			now = now.Add(time.Hour)
+			claim(name+"-a", DeliveryNew)
+
+			if err := store.Release(ctx, "unknown"); err != nil {
+				t.Errorf("Release(unknown) = %v", err)
+			}
+			if _, err := store.Claim(ctx, "", time.Minute); err == nil {
+				t.Error("Claim(\"\") succeeded")
+			}
+		})
+	}
+}
+
+func TestFileDeliveryStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileDeliveryStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := store.Claim(ctx, id, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Complete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute) // a's claim expired, b is retained
+	if n, err := store.Prune(ctx); err != nil || n != 1 {
+		t.Fatalf("Prune() = %d, %v, want 1", n, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files left, want 1", len(entries))
+	}
+	if state, _ := store.Claim(ctx, "b", time.Minute); state != DeliveryCompleted {
+		t.Errorf("Claim(b) after Prune = %v, want completed", state)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	status := http.StatusNoContent
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	})
+	h := Deduplicate(NewMemoryDeliveryStore(0))(next)
+
+	serve := func(id string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+		if id != "" {
+			req.Header.Set(DeliveryHeader, id)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A failed delivery is released and processed again on redelivery.
+	status = http.StatusInternalServerError
+	if code := serve("d1"); code != http.StatusInternalServerError || calls != 1 {
+		t.Fatalf("first delivery: status %d, calls %d", code, calls)
+	}
+	status = http.StatusNoContent
+	if code := serve("d1"); code != http.StatusNoContent || calls != 2 {
+		t.Fatalf("redelivery after failure: status %d, calls %d", code, calls)
+	}
+	// A completed delivery is acknowledged without reaching the handler.
+	if code := serve("d1"); code != http.StatusOK || calls != 2 {
+		t.Fatalf("duplicate: status %d, calls %d", code, calls)
+	}
+	// Requests without a delivery ID are not deduplicated.
+	serve("")
+	serve("")
+	if calls != 4 {
+		t.Errorf("calls without delivery ID = %d, want 4", calls)
+	}
+}
+
+func TestDeduplicate_InProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	})
+	h := Deduplicate(NewMemoryDeliveryStore(0))(next)
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+		req.Header.Set(DeliveryHeader, "d1")
+		return req
+	}
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(first, newReq())
+	}()
+	<-started
+
+	dup := httptest.NewRecorder()
+	h.ServeHTTP(dup, newReq())
+	if dup.Code != http.StatusConflict {
+		t.Errorf("concurrent duplicate status = %d, want 409", dup.Code)
+	}
+
+	close(release)
+	<-done
+	if first.Code != http.StatusAccepted {
+		t.Errorf("first status = %d, want 202", first.Code)
+	}
+}
+
+func TestDeduplicate_PanicReleases(t *testing.T) {
+	store := NewMemoryDeliveryStore(0)
+	h := Deduplicate(store)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	req.Header.Set(DeliveryHeader, "d1")
+	func() {
+		defer func() { _ = recover() }()
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if state, err := store.Claim(context.Background(), "d1", time.Minute); state != DeliveryNew || err != nil {
+		t.Errorf("Claim() after panic = %v, %v, want new", state, err)
+	}
+}
+
+func TestDeduplicate_ImplicitOK(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "write only", handler: func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) }},
+		{name: "no output", handler: func(http.ResponseWriter, *http.Request) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			h := Deduplicate(NewMemoryDeliveryStore(0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				tt.handler(w, r)
+			}))
+			for range 2 {
+				req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+				req.Header.Set(DeliveryHeader, "d1")
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Errorf("status = %d, want 200", rec.Code)
+				}
+			}
+			if calls != 1 {
+				t.Errorf("handler ran %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+// failingCompleteStore fails every Complete.
+type failingCompleteStore struct {
+	*MemoryDeliveryStore
+}
+
+func (failingCompleteStore) Complete(context.Context, string) error {
+	return errors.New("store down")
+}
+
+func TestDeduplicate_CompleteFailure(t *testing.T) {
+	store := failingCompleteStore{NewMemoryDeliveryStore(0)}
+	var reported error
+	h := Deduplicate(store, WithDeduplicateErrorHandler(func(_ *http.Request, err error) {
+		reported = err
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	req.Header.Set(DeliveryHeader, "d1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if reported == nil {
+		t.Error("Complete failure not reported")
+	}
+	// The claim is kept rather than released for reprocessing.
+	if state, err := store.Claim(context.Background(), "d1", time.Minute); state != DeliveryInProgress || err != nil {
+		t.Errorf("Claim() after failed Complete = %v, %v, want in_progress", state, err)
+	}
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+)
+
+// Delivery describes a verified webhook delivery. Middleware and
+// MiddlewareWithResolver place it in the request context; read it with
+// DeliveryFromContext instead of parsing headers again.
+type Delivery struct {
+	// Event is the event type from the X-GitHub-Event header, such as
+	// "pull_request".
+	Event string
+	// ID is the delivery GUID from the X-GitHub-Delivery header. Redeliveries
+	// keep the ID of the original delivery.
+	ID string
+	// Hook identifies the webhook that sent the delivery. It is the zero
+	// Hook when the X-GitHub-Hook-* headers are missing or malformed.
+	Hook Hook
+	// UserAgent is the User-Agent header, "GitHub-Hookshot/<id>" for
+	// deliveries sent by GitHub.
+	UserAgent string
+	// SecretIndex is the index of the secret that verified the delivery, in
+	// the list SecretSource names, in the order its resolver returned them;
+	// 0 for Middleware.
+	SecretIndex int
+	// SecretSource tells whether that secret was returned by the
+	// WithHookSecrets resolver or is the fallback secret.
+	SecretSource SecretSource
+	// PayloadSize is the size of the request body in bytes.
+	PayloadSize int
+}
+
+type deliveryKey struct{}
+
+// DeliveryFromContext returns the Delivery of a request verified by
+// Middleware. ok is false outside a verified request.
+func DeliveryFromContext(ctx context.Context) (d Delivery, ok bool) {
+	d, ok = ctx.Value(deliveryKey{}).(Delivery)
+	return d, ok
+}
+
+func withDelivery(ctx context.Context, d Delivery) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, d)
+}
+
+func newDelivery(r *http.Request, secretIndex int, source SecretSource, payloadSize int) Delivery {
+	hook, _ := HookFromRequest(r)
+	return Delivery{
+		Event:        r.Header.Get(EventHeader),
+		ID:           r.Header.Get(DeliveryHeader),
+		Hook:         hook,
+		UserAgent:    r.UserAgent(),
+		SecretIndex:  secretIndex,
+		SecretSource: source,
+		PayloadSize:  payloadSize,
+	}
+}
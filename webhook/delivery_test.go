@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware_Delivery(t *testing.T) {
+	secret := []byte("super-secret")
+	body := []byte(`{"action":"opened"}`)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    Delivery
+	}{
+		{
+			name: "all headers",
+			headers: map[string]string{
+				EventHeader:          "pull_request",
+				DeliveryHeader:       "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+				HookIDHeader:         "292430182",
+				HookTargetTypeHeader: "repository",
+				HookTargetIDHeader:   "79929171",
+				"User-Agent":         "GitHub-Hookshot/044aadd",
+			},
+			want: Delivery{
+				Event:       "pull_request",
+				ID:          "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+				Hook:        Hook{ID: 292430182, TargetType: "repository", TargetID: 79929171},
+				UserAgent:   "GitHub-Hookshot/044aadd",
+				PayloadSize: len(body),
+			},
+		},
+		{
+			name:    "no hook headers",
+			headers: map[string]string{EventHeader: "ping", "User-Agent": ""},
+			want:    Delivery{Event: "ping", PayloadSize: len(body)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Delivery
+			var ok bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, ok = DeliveryFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, sign(secret, body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			Middleware(secret)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			if !ok || got != tt.want {
+				t.Errorf("DeliveryFromContext() = %+v, %v, want %+v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryFromContext_Outside(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	if _, ok := DeliveryFromContext(req.Context()); ok {
+		t.Error("DeliveryFromContext() ok outside a verified request")
+	}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Typed payloads for the webhook events most GitHub Apps handle. The payload
+// types in events_types.go are generated by internal/eventgen from GitHub's
+// published webhook schemas (https://github.com/octokit/webhooks). They carry
+// the commonly used subset of fields listed in the generator's spec, with
+// field types and nullability taken from the schemas, and are checked by
+// tests against the example payloads in testdata. Fields can be added to the
+// spec without breaking callers; decode other events, or fields not modeled
+// here, from the raw payload.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads
+
+//go:generate go run ./internal/eventgen -schema https://unpkg.com/@octokit/webhooks-schemas/schema.json -o events_types.go
+
+// ErrUnknownEvent is returned by ParseEvent for event types without a typed
+// payload. Callers can branch with errors.Is.
+var ErrUnknownEvent = errors.New("webhook: no typed payload for event")
+
+// EventPayload is implemented by the typed payloads of this package, which
+// all embed Envelope.
+type EventPayload interface {
+	// GetEnvelope returns the fields common to all payloads.
+	GetEnvelope() *Envelope
+}
+
+// Envelope holds the fields GitHub includes in most webhook payloads. Fields
+// an event does not carry are nil or empty.
+type Envelope struct {
+	// Action is the activity that triggered the event, such as "opened".
+	// Events without activity types, such as push, leave it empty.
+	Action string `json:"action,omitempty"`
+	// Installation is the GitHub App installation the delivery was sent for.
+	// Outside installation events only ID and NodeID are set.
+	Installation *Installation `json:"installation,omitempty"`
+	// Repository is the repository the event occurred in.
+	Repository *Repository `json:"repository,omitempty"`
+	// Organization is set for events in repositories owned by an
+	// organization.
+	Organization *Organization `json:"organization,omitempty"`
+	// Sender is the user that triggered the event.
+	Sender *User `json:"sender,omitempty"`
+}
+
+// GetEnvelope implements EventPayload.
+func (e *Envelope) GetEnvelope() *Envelope { return e }
+
+// DecodeEnvelope decodes the common fields of any event's payload, including
+// events without a typed payload.
+func DecodeEnvelope(payload []byte) (*Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	return &e, nil
+}
+
+// ParseEvent decodes payload into the typed payload of event, the
+// X-GitHub-Event header value: *PingEvent for "ping", *PushEvent for "push"
+// and so on. Events without a typed payload return an error wrapping
+// ErrUnknownEvent; use DecodeEnvelope or the raw payload for them.
+func ParseEvent(event string, payload []byte) (EventPayload, error) {
+	var v EventPayload
+	switch event {
+	case "ping":
+		v = &PingEvent{}
+	case "installation":
+		v = &InstallationEvent{}
+	case "installation_repositories":
+		v = &InstallationRepositoriesEvent{}
+	case "push":
+		v = &PushEvent{}
+	case "pull_request":
+		v = &PullRequestEvent{}
+	case "issues":
+		v = &IssuesEvent{}
+	case "check_run":
+		v = &CheckRunEvent{}
+	case "check_suite":
+		v = &CheckSuiteEvent{}
+	case "workflow_run":
+		v = &WorkflowRunEvent{}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownEvent, event)
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", event, err)
+	}
+	return v, nil
+}
+
+// Parse decodes the event's payload with ParseEvent.
+func (e Event) Parse() (EventPayload, error) {
+	return ParseEvent(e.Name, e.Payload)
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, event string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", event+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		event          string
+		action         string
+		installationID int64
+		repository     string
+		sender         string
+		check          func(t *testing.T, p EventPayload)
+	}{
+		{
+			event: "ping",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*PingEvent)
+				if e.Zen != "Design for failure." || e.HookID != 109948940 || e.Hook.Type != "App" || e.Hook.AppID != 31400 {
+					t.Errorf("ping = %+v, hook %+v", e, e.Hook)
+				}
+			},
+		},
+		{
+			event:          "installation",
+			action:         "created",
+			installationID: 2,
+			sender:         "octocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*InstallationEvent)
+				inst := e.Installation
+				if inst.Account.Login != "octocat" || inst.AppID != 5725 || inst.RepositorySelection != "selected" ||
+					inst.Permissions["checks"] != "write" || len(inst.Events) != 3 || inst.SuspendedAt != nil {
+					t.Errorf("installation = %+v", inst)
+				}
+				if want := time.Date(2018, 10, 19, 18, 25, 27, 0, time.UTC); !inst.CreatedAt.Equal(want) {
+					t.Errorf("CreatedAt = %v, want %v", inst.CreatedAt, want)
+				}
+				if len(e.Repositories) != 1 || e.Repositories[0].FullName != "octocat/Hello-World" || e.Requester != nil {
+					t.Errorf("repositories = %+v, requester %+v", e.Repositories, e.Requester)
+				}
+			},
+		},
+		{
+			event:          "installation_repositories",
+			action:         "removed",
+			installationID: 2,
+			sender:         "octocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*InstallationRepositoriesEvent)
+				if e.RepositorySelection != "selected" || len(e.RepositoriesAdded) != 0 ||
+					len(e.RepositoriesRemoved) != 1 || e.RepositoriesRemoved[0].ID != 1296269 {
+					t.Errorf("installation_repositories = %+v", e)
+				}
+			},
+		},
+		{
+			event:          "push",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*PushEvent)
+				if e.Ref != "refs/heads/main" || e.BaseRef != nil || e.Pusher.Name != "Codertocat" || len(e.Commits) != 1 {
+					t.Fatalf("push = %+v", e)
+				}
+				c := e.HeadCommit
+				if c.ID != e.Commits[0].ID || c.Author.Username != "Codertocat" || c.Modified[0] != "README.md" {
+					t.Errorf("head commit = %+v", c)
+				}
+				if want := time.Date(2019, 5, 15, 20, 20, 30, 0, time.UTC); !c.Timestamp.Equal(want) {
+					t.Errorf("Timestamp = %v, want %v", c.Timestamp, want)
+				}
+			},
+		},
+		{
+			event:          "pull_request",
+			action:         "closed",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*PullRequestEvent)
+				pr := e.PullRequest
+				if e.Number != 2 || pr.Number != 2 || !pr.Merged || pr.MergedAt == nil || *pr.MergeCommitSHA == "" {
+					t.Errorf("pull request = %+v", pr)
+				}
+				if pr.Head.Ref != "changes" || pr.Base.Ref != "main" || pr.Base.Repo.FullName != "Codertocat/Hello-World" {
+					t.Errorf("head = %+v, base = %+v", pr.Head, pr.Base)
+				}
+				if len(pr.Labels) != 1 || pr.Labels[0].Name != "bug" {
+					t.Errorf("labels = %+v", pr.Labels)
+				}
+			},
+		},
+		{
+			event:          "issues",
+			action:         "opened",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*IssuesEvent)
+				if e.Issue.Number != 1 || e.Issue.State != "open" || e.Issue.ClosedAt != nil || len(e.Issue.Assignees) != 1 {
+					t.Errorf("issue = %+v", e.Issue)
+				}
+				if e.Organization == nil || e.Organization.Login != "Octocoders" {
+					t.Errorf("organization = %+v", e.Organization)
+				}
+			},
+		},
+		{
+			event:          "check_run",
+			action:         "completed",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*CheckRunEvent)
+				cr := e.CheckRun
+				if cr.Name != "Octocoders-linter" || cr.Status != "completed" || *cr.Conclusion != "success" || cr.App.Slug != "octoapp" {
+					t.Errorf("check run = %+v", cr)
+				}
+				if cr.CheckSuite.ID != 118578147 || len(cr.PullRequests) != 1 || cr.PullRequests[0].Head.Ref != "changes" {
+					t.Errorf("check suite = %+v, pull requests %+v", cr.CheckSuite, cr.PullRequests)
+				}
+				if e.RequestedAction != nil {
+					t.Errorf("requested action = %+v", e.RequestedAction)
+				}
+			},
+		},
+		{
+			event:          "check_suite",
+			action:         "requested",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				cs := p.(*CheckSuiteEvent).CheckSuite
+				if *cs.HeadBranch != "changes" || *cs.Status != "queued" || cs.Conclusion != nil || cs.LatestCheckRunsCount != 1 {
+					t.Errorf("check suite = %+v", cs)
+				}
+			},
+		},
+		{
+			event:          "workflow_run",
+			action:         "completed",
+			installationID: 2,
+			repository:     "Codertocat/Hello-World",
+			sender:         "Codertocat",
+			check: func(t *testing.T, p EventPayload) {
+				e := p.(*WorkflowRunEvent)
+				run := e.WorkflowRun
+				if e.Workflow.Path != ".github/workflows/ci.yml" || run.WorkflowID != e.Workflow.ID {
+					t.Errorf("workflow = %+v", e.Workflow)
+				}
+				if run.RunNumber != 562 || run.Event != "push" || *run.Conclusion != "failure" || run.Actor.Login != "Codertocat" {
+					t.Errorf("workflow run = %+v", run)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			payload := readFixture(t, tt.event)
+			p, err := ParseEvent(tt.event, payload)
+			if err != nil {
+				t.Fatalf("ParseEvent() error = %v", err)
+			}
+
+			env := p.GetEnvelope()
+			if env.Action != tt.action {
+				t.Errorf("Action = %q, want %q", env.Action, tt.action)
+			}
+			if tt.installationID != 0 && (env.Installation == nil || env.Installation.ID != tt.installationID) {
+				t.Errorf("Installation = %+v, want ID %d", env.Installation, tt.installationID)
+			}
+			if tt.repository != "" && (env.Repository == nil || env.Repository.FullName != tt.repository) {
+				t.Errorf("Repository = %+v, want %s", env.Repository, tt.repository)
+			}
+			if tt.sender != "" && (env.Sender == nil || env.Sender.Login != tt.sender) {
+				t.Errorf("Sender = %+v, want %s", env.Sender, tt.sender)
+			}
+
+			// DecodeEnvelope agrees with the typed payload.
+			generic, err := DecodeEnvelope(payload)
+			if err != nil {
+				t.Fatalf("DecodeEnvelope() error = %v", err)
+			}
+			if generic.Action != env.Action || (env.Installation != nil && generic.Installation.ID != env.Installation.ID) {
+				t.Errorf("DecodeEnvelope() = %+v, want %+v", generic, env)
+			}
+
+			tt.check(t, p)
+		})
+	}
+}
+
+func TestParseEvent_Errors(t *testing.T) {
+	if _, err := ParseEvent("star", []byte(`{}`)); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("ParseEvent(star) error = %v, want ErrUnknownEvent", err)
+	}
+	if _, err := ParseEvent("push", []byte(`{"ref": 1}`)); err == nil || errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("ParseEvent(malformed) error = %v", err)
+	}
+	if _, err := DecodeEnvelope([]byte(`[]`)); err == nil {
+		t.Error("DecodeEnvelope([]) succeeded")
+	}
+}
+
+func TestEvent_Parse(t *testing.T) {
+	e := Event{Name: "issues", Action: "opened", Payload: readFixture(t, "issues")}
+	p, err := e.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue, ok := p.(*IssuesEvent); !ok || issue.Issue.Number != 1 {
+		t.Errorf("Parse() = %#v", p)
+	}
+}
@@ -0,0 +1,331 @@
+// Payload types of the events ParseEvent decodes. This file is the output of
+// go generate (internal/eventgen); the spec there lists its types and fields.
+// These declarations were written to that spec before the first run against
+// the published schemas, which replaces them and marks the file generated.
+
+package webhook
+
+import "time"
+
+// User is a GitHub user, organization or bot account.
+type User struct {
+	Login   string `json:"login"`
+	ID      int64  `json:"id"`
+	NodeID  string `json:"node_id"`
+	Type    string `json:"type"` // "User", "Organization" or "Bot"
+	HTMLURL string `json:"html_url"`
+}
+
+// Organization is a GitHub organization.
+type Organization struct {
+	Login       string `json:"login"`
+	ID          int64  `json:"id"`
+	NodeID      string `json:"node_id"`
+	Description string `json:"description"`
+}
+
+// Repository is a repository as embedded in webhook payloads.
+type Repository struct {
+	ID            int64  `json:"id"`
+	NodeID        string `json:"node_id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Private       bool   `json:"private"`
+	Owner         *User  `json:"owner,omitempty"`
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+	Fork          bool   `json:"fork"`
+	Archived      bool   `json:"archived"`
+}
+
+// Installation is a GitHub App installation. Payloads of other events than
+// installation and installation_repositories only carry ID and NodeID.
+type Installation struct {
+	ID                  int64             `json:"id"`
+	NodeID              string            `json:"node_id,omitempty"`
+	Account             *User             `json:"account,omitempty"`
+	AppID               int64             `json:"app_id,omitempty"`
+	AppSlug             string            `json:"app_slug,omitempty"`
+	TargetID            int64             `json:"target_id,omitempty"`
+	TargetType          string            `json:"target_type,omitempty"`
+	RepositorySelection string            `json:"repository_selection,omitempty"` // "all" or "selected"
+	Permissions         map[string]string `json:"permissions,omitempty"`
+	Events              []string          `json:"events,omitempty"`
+	CreatedAt           *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt           *time.Time        `json:"updated_at,omitempty"`
+	SuspendedAt         *time.Time        `json:"suspended_at,omitempty"`
+	SuspendedBy         *User             `json:"suspended_by,omitempty"`
+}
+
+// InstallationRepository is a repository listed in installation payloads.
+type InstallationRepository struct {
+	ID       int64  `json:"id"`
+	NodeID   string `json:"node_id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+}
+
+// PingEvent is sent when a webhook is created.
+type PingEvent struct {
+	Envelope
+	Zen    string    `json:"zen"`
+	HookID int64     `json:"hook_id"`
+	Hook   *PingHook `json:"hook,omitempty"`
+}
+
+// PingHook is the webhook described by a PingEvent.
+type PingHook struct {
+	ID     int64    `json:"id"`
+	Type   string   `json:"type"` // "Repository", "Organization", "App", ...
+	Name   string   `json:"name"`
+	Active bool     `json:"active"`
+	Events []string `json:"events"`
+	AppID  int64    `json:"app_id,omitempty"`
+}
+
+// InstallationEvent is sent when a GitHub App is installed, uninstalled,
+// suspended, unsuspended, or its permissions are accepted. Actions:
+// "created", "deleted", "suspend", "unsuspend", "new_permissions_accepted".
+type InstallationEvent struct {
+	Envelope
+	// Repositories are the repositories the installation can access, for
+	// the "created" and "deleted" actions.
+	Repositories []InstallationRepository `json:"repositories,omitempty"`
+	Requester    *User                    `json:"requester,omitempty"`
+}
+
+// InstallationRepositoriesEvent is sent when repositories are added to or
+// removed from an installation. Actions: "added", "removed".
+type InstallationRepositoriesEvent struct {
+	Envelope
+	RepositorySelection string                   `json:"repository_selection"`
+	RepositoriesAdded   []InstallationRepository `json:"repositories_added"`
+	RepositoriesRemoved []InstallationRepository `json:"repositories_removed"`
+	Requester           *User                    `json:"requester,omitempty"`
+}
+
+// PushEvent is sent when commits or tags are pushed.
+type PushEvent struct {
+	Envelope
+	Ref        string        `json:"ref"`
+	Before     string        `json:"before"`
+	After      string        `json:"after"`
+	Created    bool          `json:"created"`
+	Deleted    bool          `json:"deleted"`
+	Forced     bool          `json:"forced"`
+	BaseRef    *string       `json:"base_ref"`
+	Compare    string        `json:"compare"`
+	Commits    []Commit      `json:"commits"`
+	HeadCommit *Commit       `json:"head_commit"`
+	Pusher     *CommitAuthor `json:"pusher,omitempty"`
+}
+
+// Commit is a commit in a PushEvent.
+type Commit struct {
+	ID        string        `json:"id"`
+	TreeID    string        `json:"tree_id"`
+	Distinct  bool          `json:"distinct"`
+	Message   string        `json:"message"`
+	Timestamp time.Time     `json:"timestamp"`
+	URL       string        `json:"url"`
+	Author    *CommitAuthor `json:"author,omitempty"`
+	Committer *CommitAuthor `json:"committer,omitempty"`
+	Added     []string      `json:"added"`
+	Removed   []string      `json:"removed"`
+	Modified  []string      `json:"modified"`
+}
+
+// CommitAuthor is the git author, committer or pusher of a push.
+type CommitAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+}
+
+// PullRequestEvent is sent for pull request activity, such as "opened",
+// "closed", "reopened", "synchronize", "edited" or "labeled".
+type PullRequestEvent struct {
+	Envelope
+	Number      int          `json:"number"`
+	PullRequest *PullRequest `json:"pull_request"`
+}
+
+// PullRequest is a pull request.
+type PullRequest struct {
+	ID             int64      `json:"id"`
+	NodeID         string     `json:"node_id"`
+	Number         int        `json:"number"`
+	State          string     `json:"state"` // "open" or "closed"
+	Title          string     `json:"title"`
+	Body           *string    `json:"body"`
+	User           *User      `json:"user"`
+	Draft          bool       `json:"draft"`
+	Merged         bool       `json:"merged"`
+	MergeCommitSHA *string    `json:"merge_commit_sha"`
+	HTMLURL        string     `json:"html_url"`
+	Head           *PRBranch  `json:"head"`
+	Base           *PRBranch  `json:"base"`
+	Labels         []Label    `json:"labels"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	MergedAt       *time.Time `json:"merged_at"`
+}
+
+// PRBranch is the head or base of a pull request.
+type PRBranch struct {
+	Label string      `json:"label"`
+	Ref   string      `json:"ref"`
+	SHA   string      `json:"sha"`
+	User  *User       `json:"user"`
+	Repo  *Repository `json:"repo"`
+}
+
+// Label is an issue or pull request label.
+type Label struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+}
+
+// IssuesEvent is sent for issue activity, such as "opened", "closed",
+// "edited", "labeled" or "assigned".
+type IssuesEvent struct {
+	Envelope
+	Issue *Issue `json:"issue"`
+}
+
+// Issue is an issue.
+type Issue struct {
+	ID        int64      `json:"id"`
+	NodeID    string     `json:"node_id"`
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      *string    `json:"body"`
+	State     string     `json:"state"` // "open" or "closed"
+	User      *User      `json:"user"`
+	Labels    []Label    `json:"labels"`
+	Assignees []User     `json:"assignees"`
+	HTMLURL   string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// App is the GitHub App that owns a check run or check suite.
+type App struct {
+	ID     int64  `json:"id"`
+	Slug   string `json:"slug"`
+	NodeID string `json:"node_id"`
+	Name   string `json:"name"`
+}
+
+// PullRequestRef is a pull request referenced by checks and workflow runs.
+type PullRequestRef struct {
+	ID     int64  `json:"id"`
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Head   struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"base"`
+}
+
+// CheckRunEvent is sent for check run activity. Actions: "created",
+// "completed", "rerequested", "requested_action".
+type CheckRunEvent struct {
+	Envelope
+	CheckRun *CheckRun `json:"check_run"`
+	// RequestedAction is set for the "requested_action" action.
+	RequestedAction *struct {
+		Identifier string `json:"identifier"`
+	} `json:"requested_action,omitempty"`
+}
+
+// CheckRun is a check run.
+type CheckRun struct {
+	ID           int64            `json:"id"`
+	NodeID       string           `json:"node_id"`
+	Name         string           `json:"name"`
+	HeadSHA      string           `json:"head_sha"`
+	ExternalID   string           `json:"external_id"`
+	Status       string           `json:"status"`     // "queued", "in_progress", "completed", ...
+	Conclusion   *string          `json:"conclusion"` // set once completed
+	HTMLURL      string           `json:"html_url"`
+	DetailsURL   string           `json:"details_url"`
+	StartedAt    *time.Time       `json:"started_at"`
+	CompletedAt  *time.Time       `json:"completed_at"`
+	CheckSuite   *CheckSuite      `json:"check_suite"`
+	App          *App             `json:"app"`
+	PullRequests []PullRequestRef `json:"pull_requests"`
+}
+
+// CheckSuiteEvent is sent for check suite activity. Actions: "completed",
+// "requested", "rerequested".
+type CheckSuiteEvent struct {
+	Envelope
+	CheckSuite *CheckSuite `json:"check_suite"`
+}
+
+// CheckSuite is a check suite.
+type CheckSuite struct {
+	ID                   int64            `json:"id"`
+	NodeID               string           `json:"node_id"`
+	HeadBranch           *string          `json:"head_branch"`
+	HeadSHA              string           `json:"head_sha"`
+	Status               *string          `json:"status"`
+	Conclusion           *string          `json:"conclusion"`
+	Before               *string          `json:"before"`
+	After                *string          `json:"after"`
+	App                  *App             `json:"app"`
+	PullRequests         []PullRequestRef `json:"pull_requests"`
+	LatestCheckRunsCount int              `json:"latest_check_runs_count"`
+	CreatedAt            *time.Time       `json:"created_at"`
+	UpdatedAt            *time.Time       `json:"updated_at"`
+}
+
+// WorkflowRunEvent is sent for GitHub Actions workflow run activity.
+// Actions: "requested", "in_progress", "completed".
+type WorkflowRunEvent struct {
+	Envelope
+	Workflow    *Workflow    `json:"workflow"`
+	WorkflowRun *WorkflowRun `json:"workflow_run"`
+}
+
+// Workflow is a GitHub Actions workflow.
+type Workflow struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	State  string `json:"state"`
+}
+
+// WorkflowRun is a GitHub Actions workflow run.
+type WorkflowRun struct {
+	ID              int64            `json:"id"`
+	NodeID          string           `json:"node_id"`
+	Name            *string          `json:"name"`
+	WorkflowID      int64            `json:"workflow_id"`
+	HeadBranch      *string          `json:"head_branch"`
+	HeadSHA         string           `json:"head_sha"`
+	Path            string           `json:"path"`
+	RunNumber       int              `json:"run_number"`
+	RunAttempt      int              `json:"run_attempt"`
+	Event           string           `json:"event"`
+	Status          *string          `json:"status"`
+	Conclusion      *string          `json:"conclusion"`
+	HTMLURL         string           `json:"html_url"`
+	Actor           *User            `json:"actor"`
+	TriggeringActor *User            `json:"triggering_actor"`
+	PullRequests    []PullRequestRef `json:"pull_requests"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	RunStartedAt    *time.Time       `json:"run_started_at"`
+}
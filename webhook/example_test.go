@@ -19,10 +19,9 @@ func ExampleMiddleware() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
 		// The body reaching this handler is already authenticated.
-		event := r.Header.Get(webhook.EventHeader)
-		delivery := r.Header.Get(webhook.DeliveryHeader)
+		delivery, _ := webhook.DeliveryFromContext(r.Context())
 
-		log.Printf("received %s delivery=%s", event, delivery)
+		log.Printf("received %s delivery=%s hook=%d", delivery.Event, delivery.ID, delivery.Hook.ID)
 		w.WriteHeader(http.StatusNoContent)
 	})
 
@@ -48,3 +47,21 @@ func ExampleVerify() {
 		fmt.Println("wrong secret or tampered body")
 	}
 }
+
+// Router dispatches verified deliveries by event and action. Deliveries
+// without a matching route are acknowledged with 202 Accepted.
+func ExampleRouter() {
+	secret := []byte(os.Getenv("GITHUB_WEBHOOK_SECRET"))
+
+	router := webhook.NewRouter()
+	router.OnFunc("pull_request", "opened", func(w http.ResponseWriter, r *http.Request) {
+		ev, _ := webhook.EventFromContext(r.Context())
+		log.Printf("pull request opened: %d bytes", len(ev.Payload))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.OnFunc("push", "", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Fatal(http.ListenAndServe(":8080", webhook.Middleware(secret)(router)))
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Header names identifying the webhook a delivery was sent by.
+const (
+	HookIDHeader         = "X-GitHub-Hook-ID"
+	HookTargetTypeHeader = "X-GitHub-Hook-Installation-Target-Type"
+	HookTargetIDHeader   = "X-GitHub-Hook-Installation-Target-ID"
+)
+
+// ErrUnknownHook is returned when a delivery does not identify a webhook a
+// secret is known for: the hook headers are missing or malformed, or the
+// HookSecretFunc returned no secret, and no fallback secret is configured.
+// Such deliveries are rejected with 401 Unauthorized.
+var ErrUnknownHook = errors.New("webhook: unknown hook")
+
+// Hook identifies the webhook that sent a delivery, as reported by the
+// X-GitHub-Hook-* headers. The headers are not covered by the signature, so
+// they only select the secret to verify with and must not be trusted on
+// their own.
+type Hook struct {
+	// ID is the ID of the webhook.
+	ID int64
+	// TargetType is the kind of resource the webhook is installed on, such
+	// as "repository", "organization", "business" or "integration" (a GitHub
+	// App).
+	TargetType string
+	// TargetID is the ID of that resource.
+	TargetID int64
+}
+
+// HookFromRequest parses the hook headers of r. It returns an error wrapping
+// ErrUnknownHook when a header is missing or malformed.
+func HookFromRequest(r *http.Request) (Hook, error) {
+	id, err := strconv.ParseInt(r.Header.Get(HookIDHeader), 10, 64)
+	if err != nil {
+		return Hook{}, fmt.Errorf("%w: invalid %s header", ErrUnknownHook, HookIDHeader)
+	}
+	targetType := r.Header.Get(HookTargetTypeHeader)
+	if targetType == "" {
+		return Hook{}, fmt.Errorf("%w: missing %s header", ErrUnknownHook, HookTargetTypeHeader)
+	}
+	targetID, err := strconv.ParseInt(r.Header.Get(HookTargetIDHeader), 10, 64)
+	if err != nil {
+		return Hook{}, fmt.Errorf("%w: invalid %s header", ErrUnknownHook, HookTargetIDHeader)
+	}
+	return Hook{ID: id, TargetType: targetType, TargetID: targetID}, nil
+}
+
+// HookSecretFunc returns the secrets accepted for deliveries of hook, most
+// preferred first. It returns no secret for hooks it does not know.
+type HookSecretFunc func(ctx context.Context, hook Hook) ([][]byte, error)
+
+// WithHookSecrets resolves the secret of every delivery from its hook
+// headers, for receivers serving many organization and repository webhooks
+// at one endpoint.
+//
+// The secret passed to Middleware, or the resolver passed to
+// MiddlewareWithResolver, remains the fallback: deliveries without hook
+// headers, or of hooks fn returns no secret for, are verified with it, so a
+// GitHub App webhook and per-hook secrets can share an endpoint. Pass a nil
+// secret to Middleware to accept known hooks only.
+//
+// Deliveries fail closed: when neither fn nor the fallback has a secret the
+// delivery is rejected with 401 Unauthorized (ErrUnknownHook), and an error
+// from fn or the fallback resolver with 500 Internal Server Error
+// (ErrNoSecret).
+func WithHookSecrets(fn HookSecretFunc) MiddlewareOpt {
+	return func(c *middlewareConfig) { c.hookSecrets = fn }
+}
+
+// SecretSource tells which list of secrets the secret that verified a
+// delivery was taken from.
+type SecretSource int
+
+const (
+	// SecretSourceFallback is the secret passed to Middleware, or the secrets
+	// returned by the resolver passed to MiddlewareWithResolver. Without
+	// WithHookSecrets every delivery is verified with it.
+	SecretSourceFallback SecretSource = iota
+	// SecretSourceHook is the secrets returned by the HookSecretFunc passed
+	// to WithHookSecrets.
+	SecretSourceHook
+)
+
+// String returns the lowercase name of the source.
+func (s SecretSource) String() string {
+	if s == SecretSourceHook {
+		return "hook"
+	}
+	return "fallback"
+}
+
+// sourcedResolver is a SecretResolver that also reports which list the
+// secrets were taken from.
+type sourcedResolver func(r *http.Request) ([][]byte, SecretSource, error)
+
+// fallbackResolver resolves the secrets of every delivery with resolve.
+func fallbackResolver(resolve SecretResolver) sourcedResolver {
+	return func(r *http.Request) ([][]byte, SecretSource, error) {
+		secrets, err := resolve(r)
+		return secrets, SecretSourceFallback, err
+	}
+}
+
+// hookResolver resolves the secrets of a delivery with fn, falling back to
+// fallback for deliveries fn has no secret for. A nil fallback rejects those
+// deliveries with ErrUnknownHook. The secrets are returned as the resolver
+// returned them, so a matched index refers to that list.
+func hookResolver(fn HookSecretFunc, fallback SecretResolver) sourcedResolver {
+	return func(r *http.Request) ([][]byte, SecretSource, error) {
+		hook, unknown := HookFromRequest(r)
+		if unknown == nil {
+			secrets, err := fn(r.Context(), hook)
+			if err != nil {
+				return nil, SecretSourceHook, fmt.Errorf("failed to resolve secret of hook %d: %w", hook.ID, err)
+			}
+			if len(secrets) > 0 {
+				return secrets, SecretSourceHook, nil
+			}
+			unknown = fmt.Errorf("%w: no secret for hook %d on %s %d", ErrUnknownHook, hook.ID, hook.TargetType, hook.TargetID)
+		}
+
+		if fallback == nil {
+			return nil, SecretSourceFallback, unknown
+		}
+		secrets, err := fallback(r)
+		if err != nil {
+			return nil, SecretSourceFallback, err
+		}
+		if len(secrets) == 0 {
+			return nil, SecretSourceFallback, unknown
+		}
+		return secrets, SecretSourceFallback, nil
+	}
+}
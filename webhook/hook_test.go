@@ -0,0 +1,242 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithHookSecrets(t *testing.T) {
+	body := []byte(`{"action":"created"}`)
+	secrets := map[int64][]byte{
+		1: []byte("org-hook-secret"),
+		2: []byte("repo-hook-secret"),
+	}
+	var gotHook Hook
+	resolve := func(ctx context.Context, hook Hook) ([][]byte, error) {
+		if ctx == nil {
+			t.Error("nil context")
+		}
+		gotHook = hook
+		if hook.ID == 99 {
+			return nil, errors.New("secret store unavailable")
+		}
+		if s, ok := secrets[hook.ID]; ok {
+			return [][]byte{s}, nil
+		}
+		return nil, nil
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := Middleware(nil, WithHookSecrets(resolve))(next)
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		secret     []byte
+		wantStatus int
+		wantHook   Hook
+	}{
+		{
+			name:       "organization hook",
+			headers:    map[string]string{HookIDHeader: "1", HookTargetTypeHeader: "organization", HookTargetIDHeader: "10"},
+			secret:     secrets[1],
+			wantStatus: http.StatusNoContent,
+			wantHook:   Hook{ID: 1, TargetType: "organization", TargetID: 10},
+		},
+		{
+			name:       "repository hook",
+			headers:    map[string]string{HookIDHeader: "2", HookTargetTypeHeader: "repository", HookTargetIDHeader: "20"},
+			secret:     secrets[2],
+			wantStatus: http.StatusNoContent,
+			wantHook:   Hook{ID: 2, TargetType: "repository", TargetID: 20},
+		},
+		{
+			name:       "secret of another hook",
+			headers:    map[string]string{HookIDHeader: "2", HookTargetTypeHeader: "repository", HookTargetIDHeader: "20"},
+			secret:     secrets[1],
+			wantStatus: http.StatusUnauthorized,
+			wantHook:   Hook{ID: 2, TargetType: "repository", TargetID: 20},
+		},
+		{
+			name:       "unknown hook",
+			headers:    map[string]string{HookIDHeader: "3", HookTargetTypeHeader: "repository", HookTargetIDHeader: "30"},
+			secret:     nil,
+			wantStatus: http.StatusUnauthorized,
+			wantHook:   Hook{ID: 3, TargetType: "repository", TargetID: 30},
+		},
+		{
+			name:       "missing hook ID",
+			headers:    map[string]string{HookTargetTypeHeader: "repository", HookTargetIDHeader: "20"},
+			secret:     secrets[2],
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "malformed target ID",
+			headers:    map[string]string{HookIDHeader: "2", HookTargetTypeHeader: "repository", HookTargetIDHeader: "x"},
+			secret:     secrets[2],
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "resolver failure",
+			headers:    map[string]string{HookIDHeader: "99", HookTargetTypeHeader: "repository", HookTargetIDHeader: "1"},
+			secret:     secrets[1],
+			wantStatus: http.StatusInternalServerError,
+			wantHook:   Hook{ID: 99, TargetType: "repository", TargetID: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHook = Hook{}
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, sign(tt.secret, body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotHook != tt.wantHook {
+				t.Errorf("resolver got hook %+v, want %+v", gotHook, tt.wantHook)
+			}
+		})
+	}
+}
+
+func TestWithHookSecrets_ErrorHandler(t *testing.T) {
+	var gotErr error
+	h := Middleware(nil,
+		WithHookSecrets(func(context.Context, Hook) ([][]byte, error) { return nil, nil }),
+		WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusForbidden)
+		}),
+	)(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set(HookIDHeader, "1")
+	req.Header.Set(HookTargetTypeHeader, "repository")
+	req.Header.Set(HookTargetIDHeader, "1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !errors.Is(gotErr, ErrUnknownHook) {
+		t.Errorf("error handler got %v, want ErrUnknownHook", gotErr)
+	}
+}
+
+func TestWithHookSecrets_Fallback(t *testing.T) {
+	body := []byte(`{"action":"created"}`)
+	appSecret, hookSecret := []byte("app-secret"), []byte("hook-secret")
+	resolve := func(_ context.Context, hook Hook) ([][]byte, error) {
+		if hook.ID == 1 {
+			return [][]byte{hookSecret}, nil
+		}
+		return nil, nil
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	hookHeaders := func(id string) map[string]string {
+		return map[string]string{HookIDHeader: id, HookTargetTypeHeader: "repository", HookTargetIDHeader: "20"}
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		headers    map[string]string
+		secret     []byte
+		wantStatus int
+	}{
+		{"known hook", Middleware(appSecret, WithHookSecrets(resolve))(next), hookHeaders("1"), hookSecret, http.StatusNoContent},
+		{"unknown hook uses the static secret", Middleware(appSecret, WithHookSecrets(resolve))(next), hookHeaders("2"), appSecret, http.StatusNoContent},
+		{"no hook headers use the static secret", Middleware(appSecret, WithHookSecrets(resolve))(next), nil, appSecret, http.StatusNoContent},
+		{"known hook rejects the static secret", Middleware(appSecret, WithHookSecrets(resolve))(next), hookHeaders("1"), appSecret, http.StatusUnauthorized},
+		{"resolver fallback", MiddlewareWithResolver(StaticSecrets(appSecret), WithHookSecrets(resolve))(next), hookHeaders("2"), appSecret, http.StatusNoContent},
+		{"nil static secret", Middleware(nil, WithHookSecrets(resolve))(next), hookHeaders("2"), nil, http.StatusUnauthorized},
+		{"failing fallback", MiddlewareWithResolver(func(*http.Request) ([][]byte, error) {
+			return nil, errors.New("vault sealed")
+		}, WithHookSecrets(resolve))(next), hookHeaders("2"), appSecret, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, sign(tt.secret, body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithHookSecrets_MatchedSecret(t *testing.T) {
+	body := []byte(`{"action":"created"}`)
+	newHook, oldHook := []byte("new-hook-secret"), []byte("old-hook-secret")
+	newApp, oldApp := []byte("new-app-secret"), []byte("old-app-secret")
+	resolve := func(_ context.Context, hook Hook) ([][]byte, error) {
+		if hook.ID == 1 {
+			return [][]byte{newHook, oldHook}, nil
+		}
+		return nil, nil
+	}
+	// The empty secret stays in the list, so indexes match what the
+	// resolver returned.
+	fallback := StaticSecrets([]byte{}, newApp, oldApp)
+
+	var got Delivery
+	h := MiddlewareWithResolver(fallback, WithHookSecrets(resolve))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = DeliveryFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		hookID     string
+		secret     []byte
+		wantIndex  int
+		wantSource SecretSource
+	}{
+		{"new hook secret", "1", newHook, 0, SecretSourceHook},
+		{"old hook secret", "1", oldHook, 1, SecretSourceHook},
+		{"new fallback secret", "2", newApp, 1, SecretSourceFallback},
+		{"old fallback secret", "2", oldApp, 2, SecretSourceFallback},
+		{"no hook headers", "", oldApp, 2, SecretSourceFallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = Delivery{}
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, sign(tt.secret, body))
+			if tt.hookID != "" {
+				req.Header.Set(HookIDHeader, tt.hookID)
+				req.Header.Set(HookTargetTypeHeader, "repository")
+				req.Header.Set(HookTargetIDHeader, "20")
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			if got.SecretIndex != tt.wantIndex || got.SecretSource != tt.wantSource {
+				t.Errorf("matched secret %d from %v, want %d from %v", got.SecretIndex, got.SecretSource, tt.wantIndex, tt.wantSource)
+			}
+		})
+	}
+}
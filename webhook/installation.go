@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoInstallation is reported for deliveries whose payload lacks
+// installation.id, such as repository or organization webhooks not sent on
+// behalf of a GitHub App.
+var ErrNoInstallation = errors.New("webhook: payload has no installation")
+
+// InstallationTokenSources returns token sources authenticating as GitHub
+// App installations. *githubauth.InstallationCache implements it; returning
+// a shared source per installation lets handlers reuse cached tokens.
+type InstallationTokenSources interface {
+	TokenSource(installationID int64) oauth2.TokenSource
+}
+
+// InstallationClient authenticates as the installation a delivery was sent
+// for.
+type InstallationClient struct {
+	// ID is the payload's installation.id.
+	ID int64
+	// TokenSource returns installation access tokens for ID.
+	TokenSource oauth2.TokenSource
+}
+
+// HTTPClient returns an *http.Client authenticated as the installation. As
+// with oauth2.NewClient, ctx only supplies the base client.
+func (c InstallationClient) HTTPClient(ctx context.Context) *http.Client {
+	return oauth2.NewClient(ctx, c.TokenSource)
+}
+
+// InstallationHandlerFunc handles a delivery on behalf of the installation
+// it was sent for.
+type InstallationHandlerFunc func(w http.ResponseWriter, r *http.Request, inst InstallationClient)
+
+// InstallationHandler returns an http.Handler calling fn with a client for
+// the delivery's installation.id, drawn from sources:
+//
+//	appSrc, _ := githubauth.NewApplicationTokenSource(clientID, privateKey)
+//	installations := githubauth.NewInstallationCache(appSrc)
+//
+//	router := webhook.NewRouter()
+//	router.On("issues", "opened", webhook.InstallationHandler(installations,
+//		func(w http.ResponseWriter, r *http.Request, inst webhook.InstallationClient) {
+//			client := inst.HTTPClient(r.Context())
+//			// call the GitHub API as the installation
+//		}))
+//
+// Behind a Router the payload parsed by the Router is reused; otherwise the
+// body is read and restored for fn. Deliveries without an installation get
+// 400 Bad Request.
+func InstallationHandler(sources InstallationTokenSources, fn InstallationHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := requestPayload(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		env, err := DecodeEnvelope(payload)
+		if err != nil {
+			http.Error(w, "malformed payload", http.StatusBadRequest)
+			return
+		}
+		if env.Installation == nil || env.Installation.ID == 0 {
+			http.Error(w, ErrNoInstallation.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id := env.Installation.ID
+		fn(w, r, InstallationClient{ID: id, TokenSource: sources.TokenSource(id)})
+	})
+}
+
+// requestPayload returns the JSON payload of r, from the Router's Event when
+// present and from the body otherwise, which is restored for later readers.
+func requestPayload(r *http.Request) ([]byte, error) {
+	if ev, ok := EventFromContext(r.Context()); ok {
+		return ev.Payload, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.New("failed to read body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return jsonPayload(r.Header.Get("Content-Type"), body)
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeSources returns a static token naming the installation.
+type fakeSources struct{ requested []int64 }
+
+func (f *fakeSources) TokenSource(id int64) oauth2.TokenSource {
+	f.requested = append(f.requested, id)
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token-" + strconv.FormatInt(id, 10)})
+}
+
+func TestInstallationHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		routed      bool
+		wantCode    int
+		wantID      int64
+	}{
+		{name: "json", body: `{"action":"opened","installation":{"id":7}}`, wantCode: http.StatusNoContent, wantID: 7},
+		{name: "routed", body: `{"action":"opened","installation":{"id":7}}`, routed: true, wantCode: http.StatusNoContent, wantID: 7},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "payload=" + url.QueryEscape(`{"installation":{"id":3}}`),
+			wantCode:    http.StatusNoContent,
+			wantID:      3,
+		},
+		{name: "no installation", body: `{"zen":"hi"}`, wantCode: http.StatusBadRequest},
+		{name: "malformed", body: `{`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := &fakeSources{}
+			var got InstallationClient
+			var body string
+			var h http.Handler = InstallationHandler(sources, func(w http.ResponseWriter, r *http.Request, inst InstallationClient) {
+				got = inst
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			if tt.routed {
+				router := NewRouter()
+				router.On("issues", "", h)
+				h = router
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set(EventHeader, "issues")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantID == 0 {
+				if len(sources.requested) != 0 {
+					t.Errorf("token sources requested for %v", sources.requested)
+				}
+				return
+			}
+			if got.ID != tt.wantID || len(sources.requested) != 1 || sources.requested[0] != tt.wantID {
+				t.Errorf("installation = %d, requested %v, want %d", got.ID, sources.requested, tt.wantID)
+			}
+			if tok, err := got.TokenSource.Token(); err != nil || tok.AccessToken != "token-"+strconv.FormatInt(tt.wantID, 10) {
+				t.Errorf("Token() = %v, %v", tok, err)
+			}
+			if body != tt.body {
+				t.Errorf("handler read body %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestInstallationClient_HTTPClient(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	inst := InstallationClient{ID: 1, TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ghs_x"})}
+	resp, err := inst.HTTPClient(t.Context()).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if auth != "Bearer ghs_x" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
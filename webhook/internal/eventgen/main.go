@@ -0,0 +1,184 @@
+// Command eventgen generates the webhook event payload types from GitHub's
+// published webhook schemas (https://github.com/octokit/webhooks). The types
+// and fields it emits are listed in spec.go; their Go types and nullability
+// come from the schema unless the spec sets them.
+//
+// Usage:
+//
+//	go run ./internal/eventgen -schema schema.json -o events_types.go
+//
+// -schema is a file path or an http(s) URL.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jferrl/go-githubauth/internal/atomicfile"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "webhook schema file or URL")
+	out := flag.String("o", "events_types.go", "output file")
+	pkg := flag.String("pkg", "webhook", "package name of the output file")
+	flag.Parse()
+
+	if err := run(*schemaPath, *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "eventgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaPath, out, pkg string) error {
+	if schemaPath == "" {
+		return fmt.Errorf("-schema is required")
+	}
+	b, err := load(schemaPath)
+	if err != nil {
+		return err
+	}
+	var root schema
+	if err := json.Unmarshal(b, &root); err != nil {
+		return fmt.Errorf("failed to decode schema: %w", err)
+	}
+	src, err := generate(&root, pkg, types)
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(out, src, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return nil
+}
+
+// load reads the schema from a file or, for http(s) URLs, over the network.
+func load(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		return b, nil
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch schema: %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema: %w", err)
+	}
+	return b, nil
+}
+
+// header starts every generated file.
+const header = "// Code generated by eventgen from GitHub's webhook schemas. DO NOT EDIT.\n\n"
+
+// generator holds the schema types are generated from.
+type generator struct {
+	root *schema
+	// named maps definitions to the Go types generated from them, so
+	// references to them are typed by name.
+	named map[string]string
+}
+
+// generate returns the gofmt-ed source of package pkg declaring specs, with
+// field types taken from root.
+func generate(root *schema, pkg string, specs []typeSpec) ([]byte, error) {
+	g := &generator{root: root, named: make(map[string]string)}
+	for _, t := range specs {
+		if t.Source != "" && !strings.Contains(t.Source, "/") {
+			g.named[t.Source] = t.Name
+		}
+	}
+
+	var body bytes.Buffer
+	for _, t := range specs {
+		if err := g.writeType(&body, t); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if strings.Contains(body.String(), "time.") {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %w", err)
+	}
+	return src, nil
+}
+
+// writeType writes the declaration of t.
+func (g *generator) writeType(w *bytes.Buffer, t typeSpec) error {
+	var props map[string]*schema
+	if t.Source != "" {
+		s, err := g.lookup(t.Source)
+		if err != nil {
+			return err
+		}
+		if props, err = g.properties(s); err != nil {
+			return fmt.Errorf("%s: %w", t.Source, err)
+		}
+	}
+
+	writeComment(w, t.Doc)
+	fmt.Fprintf(w, "type %s struct {\n", t.Name)
+	if t.Envelope {
+		w.WriteString("Envelope\n")
+	}
+	for _, f := range t.Fields {
+		typ := f.Type
+		if typ == "" {
+			p, ok := props[f.JSON]
+			if !ok {
+				return fmt.Errorf("%s: no property %q", t.Source, f.JSON)
+			}
+			var err error
+			if typ, err = g.goType(p); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Source, f.JSON, err)
+			}
+		}
+		tag := f.JSON
+		if f.OmitEmpty {
+			tag += ",omitempty"
+		}
+		writeComment(w, f.Doc)
+		fmt.Fprintf(w, "%s %s `json:%q`", f.Name, typ, tag)
+		if f.Comment != "" {
+			fmt.Fprintf(w, " // %s", f.Comment)
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString("}\n\n")
+	return nil
+}
+
+// writeComment writes doc as line comments, one per line of doc.
+func writeComment(w *bytes.Buffer, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(w, "// %s\n", line)
+	}
+}
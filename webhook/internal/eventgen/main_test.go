@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func readSchema(t *testing.T) *schema {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+func TestGenerate(t *testing.T) {
+	specs := []typeSpec{
+		{
+			Name:   "User",
+			Doc:    "User is a user.",
+			Source: "user",
+			Fields: []fieldSpec{
+				{JSON: "login", Name: "Login"},
+				{JSON: "id", Name: "ID"},
+				{JSON: "site_admin", Name: "SiteAdmin", OmitEmpty: true},
+			},
+		},
+		{
+			Name:   "Label",
+			Doc:    "Label is a label.",
+			Source: "label",
+			Fields: []fieldSpec{
+				{JSON: "name", Name: "Name"},
+				{JSON: "score", Name: "Score"},
+			},
+		},
+		{
+			Name:     "ThingEvent",
+			Doc:      "ThingEvent is a thing.\nIt has two doc lines.",
+			Source:   "thing$created",
+			Envelope: true,
+			Fields: []fieldSpec{
+				{JSON: "created_at", Name: "CreatedAt"},
+				{JSON: "closed_at", Name: "ClosedAt"},
+				{JSON: "title", Name: "Title", Comment: "may be null"},
+				{JSON: "owner", Name: "Owner", Doc: "Owner owns the thing."},
+				{JSON: "assignee", Name: "Assignee"},
+				{JSON: "labels", Name: "Labels"},
+				{JSON: "tags", Name: "Tags"},
+				{JSON: "permissions", Name: "Permissions"},
+				{JSON: "detail", Name: "Detail", Type: "map[string]any"},
+			},
+		},
+	}
+	got, err := generate(readSchema(t), "things", specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "types.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("generate() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec typeSpec
+		want string
+	}{
+		{
+			name: "unknown definition",
+			spec: typeSpec{Name: "T", Source: "missing"},
+			want: `unknown definition "missing"`,
+		},
+		{
+			name: "unknown property",
+			spec: typeSpec{Name: "T", Source: "user", Fields: []fieldSpec{{JSON: "email", Name: "Email"}}},
+			want: `no property "email"`,
+		},
+		{
+			name: "inline object",
+			spec: typeSpec{Name: "T", Source: "thing$created", Fields: []fieldSpec{{JSON: "detail", Name: "Detail"}}},
+			want: "object without a Go type",
+		},
+		{
+			name: "unnamed definition",
+			spec: typeSpec{Name: "T", Source: "thing$created", Fields: []fieldSpec{{JSON: "owner", Name: "Owner"}}},
+			want: "object without a Go type",
+		},
+		{
+			name: "not an array",
+			spec: typeSpec{Name: "T", Source: "user/[]"},
+			want: "not an array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate(readSchema(t), "things", []typeSpec{tt.spec})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("generate() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestSpecMatchesTypes checks that the payload types in the webhook package
+// declare the types and fields of the spec, in order, so the next generator
+// run changes field types at most.
+func TestSpecMatchesTypes(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), filepath.Join("..", "..", "events_types.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []typeSpec
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range g.Specs {
+			ts := s.(*ast.TypeSpec)
+			typ := typeSpec{Name: ts.Name.Name}
+			for _, field := range ts.Type.(*ast.StructType).Fields.List {
+				if len(field.Names) == 0 {
+					typ.Envelope = true
+					continue
+				}
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				name, opts, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+				typ.Fields = append(typ.Fields, fieldSpec{
+					JSON:      name,
+					Name:      field.Names[0].Name,
+					OmitEmpty: opts == "omitempty",
+				})
+			}
+			got = append(got, typ)
+		}
+	}
+
+	var want []typeSpec
+	for _, typ := range types {
+		w := typeSpec{Name: typ.Name, Envelope: typ.Envelope}
+		for _, f := range typ.Fields {
+			w.Fields = append(w.Fields, fieldSpec{JSON: f.JSON, Name: f.Name, OmitEmpty: f.OmitEmpty})
+		}
+		want = append(want, w)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events_types.go declares\n%+v\nspec lists\n%+v", got, want)
+	}
+}
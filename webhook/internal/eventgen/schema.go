@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schema is the subset of JSON Schema (draft 7) used by GitHub's webhook
+// schemas.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 typeList           `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*schema `json:"properties"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	AllOf                []*schema          `json:"allOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	Definitions          map[string]*schema `json:"definitions"`
+}
+
+// typeList is the "type" keyword, a single type name or a list of them.
+type typeList []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *typeList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("failed to decode type: %w", err)
+	}
+	*t = many
+	return nil
+}
+
+// refPrefix starts the references to definitions of the root schema.
+const refPrefix = "#/definitions/"
+
+// resolve follows the $ref chain of s, returning the schema it ends at and
+// the name of the last definition referenced, if any.
+func (g *generator) resolve(s *schema) (*schema, string, error) {
+	name := ""
+	for seen := 0; s.Ref != ""; seen++ {
+		if seen > 32 {
+			return nil, "", fmt.Errorf("reference cycle at %q", s.Ref)
+		}
+		ref, ok := strings.CutPrefix(s.Ref, refPrefix)
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported reference %q", s.Ref)
+		}
+		ref = strings.NewReplacer("~1", "/", "~0", "~").Replace(ref)
+		def, ok := g.root.Definitions[ref]
+		if !ok {
+			return nil, "", fmt.Errorf("reference to unknown definition %q", ref)
+		}
+		s, name = def, ref
+	}
+	return s, name, nil
+}
+
+// properties returns the properties of the object described by s, merging
+// those of its allOf, anyOf and oneOf members. The first declaration of a
+// property wins.
+func (g *generator) properties(s *schema) (map[string]*schema, error) {
+	props := make(map[string]*schema)
+	var collect func(s *schema, depth int) error
+	collect = func(s *schema, depth int) error {
+		if depth > 32 {
+			return fmt.Errorf("schema nested too deeply")
+		}
+		s, _, err := g.resolve(s)
+		if err != nil {
+			return err
+		}
+		for name, p := range s.Properties {
+			if _, ok := props[name]; !ok {
+				props[name] = p
+			}
+		}
+		for _, list := range [][]*schema{s.AllOf, s.AnyOf, s.OneOf} {
+			for _, m := range list {
+				if err := collect(m, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := collect(s, 0); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// lookup returns the schema at path: a definition name followed by property
+// names, with "[]" stepping into array items, e.g. "push$event/commits/[]".
+func (g *generator) lookup(path string) (*schema, error) {
+	steps := strings.Split(path, "/")
+	s, ok := g.root.Definitions[steps[0]]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown definition %q", path, steps[0])
+	}
+	for _, step := range steps[1:] {
+		if step == "[]" {
+			r, _, err := g.resolve(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if r.Items == nil {
+				return nil, fmt.Errorf("%s: not an array", path)
+			}
+			s = r.Items
+			continue
+		}
+		props, err := g.properties(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p, ok := props[step]
+		if !ok {
+			return nil, fmt.Errorf("%s: no property %q", path, step)
+		}
+		s = p
+	}
+	return s, nil
+}
+
+// goType returns the Go type of values described by s. Objects are typed
+// only when they resolve to a definition with a Go type of its own, or are
+// maps; other objects need a type in the spec.
+func (g *generator) goType(s *schema) (string, error) {
+	s, ref, err := g.resolve(s)
+	if err != nil {
+		return "", err
+	}
+	if name, ok := g.named[ref]; ok {
+		return "*" + name, nil
+	}
+
+	nullable := false
+	var types []string
+	for _, t := range s.Type {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		types = append(types, t)
+	}
+
+	if len(types) == 0 {
+		// A union such as {"anyOf": [{"$ref": ...}, {"type": "null"}]}.
+		var alts []*schema
+		for _, a := range append(append([]*schema{}, s.AnyOf...), s.OneOf...) {
+			r, _, err := g.resolve(a)
+			if err != nil {
+				return "", err
+			}
+			if len(r.Type) == 1 && r.Type[0] == "null" {
+				nullable = true
+				continue
+			}
+			alts = append(alts, a)
+		}
+		if len(alts) != 1 {
+			return "", fmt.Errorf("no single type among %d alternatives", len(alts))
+		}
+		t, err := g.goType(alts[0])
+		if err != nil {
+			return "", err
+		}
+		return pointer(t, nullable), nil
+	}
+	if len(types) != 1 {
+		return "", fmt.Errorf("no single type among %v", types)
+	}
+
+	var t string
+	switch types[0] {
+	case "string":
+		t = "string"
+		if s.Format == "date-time" {
+			t = "time.Time"
+		}
+	case "integer":
+		t = "int64"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		elem, err := g.goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		// Slices are nil when null and hold their elements by value.
+		return "[]" + strings.TrimPrefix(elem, "*"), nil
+	case "object":
+		if len(s.Properties) > 0 || len(s.AdditionalProperties) == 0 {
+			return "", fmt.Errorf("object without a Go type")
+		}
+		var elem schema
+		if err := json.Unmarshal(s.AdditionalProperties, &elem); err != nil {
+			return "", fmt.Errorf("object without a Go type")
+		}
+		v, err := g.goType(&elem)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + strings.TrimPrefix(v, "*"), nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", types[0])
+	}
+	return pointer(t, nullable), nil
+}
+
+// pointer returns t, as a pointer when nullable.
+func pointer(t string, nullable bool) string {
+	if !nullable || strings.HasPrefix(t, "*") || strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[") {
+		return t
+	}
+	return "*" + t
+}
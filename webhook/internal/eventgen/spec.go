@@ -0,0 +1,435 @@
+package main
+
+// typeSpec describes a generated struct type.
+type typeSpec struct {
+	Name string
+	Doc  string
+	// Source is the schema path field types are looked up in: a definition
+	// name, optionally followed by property names and "[]" for array items,
+	// e.g. "push$event/commits/[]". References to a definition that is the
+	// Source of a type are typed as a pointer to that type.
+	Source string
+	// Envelope embeds the Envelope type, for event payloads.
+	Envelope bool
+	Fields   []fieldSpec
+}
+
+// fieldSpec describes a field of a generated struct type.
+type fieldSpec struct {
+	JSON string
+	Name string
+	// Type overrides the Go type derived from the schema, e.g. for fields
+	// whose schema is an inline object or a union of types.
+	Type      string
+	OmitEmpty bool
+	Doc       string
+	// Comment is a trailing line comment.
+	Comment string
+}
+
+// types lists the generated types, in output order. Fields are a subset of
+// the schema's: add fields here, not to the generated file.
+var types = []typeSpec{
+	{
+		Name:   "User",
+		Doc:    "User is a GitHub user, organization or bot account.",
+		Source: "user",
+		Fields: []fieldSpec{
+			{JSON: "login", Name: "Login"},
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "type", Name: "Type", Type: "string", Comment: `"User", "Organization" or "Bot"`},
+			{JSON: "html_url", Name: "HTMLURL"},
+		},
+	},
+	{
+		Name:   "Organization",
+		Doc:    "Organization is a GitHub organization.",
+		Source: "organization",
+		Fields: []fieldSpec{
+			{JSON: "login", Name: "Login"},
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "description", Name: "Description", Type: "string"},
+		},
+	},
+	{
+		Name:   "Repository",
+		Doc:    "Repository is a repository as embedded in webhook payloads.",
+		Source: "repository",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "full_name", Name: "FullName"},
+			{JSON: "private", Name: "Private"},
+			{JSON: "owner", Name: "Owner", OmitEmpty: true},
+			{JSON: "html_url", Name: "HTMLURL"},
+			{JSON: "default_branch", Name: "DefaultBranch"},
+			{JSON: "fork", Name: "Fork"},
+			{JSON: "archived", Name: "Archived"},
+		},
+	},
+	{
+		Name: "Installation",
+		Doc: "Installation is a GitHub App installation. Payloads of other events than\n" +
+			"installation and installation_repositories only carry ID and NodeID.",
+		Source: "installation",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID", Type: "string", OmitEmpty: true},
+			{JSON: "account", Name: "Account", OmitEmpty: true},
+			{JSON: "app_id", Name: "AppID", OmitEmpty: true},
+			{JSON: "app_slug", Name: "AppSlug", OmitEmpty: true},
+			{JSON: "target_id", Name: "TargetID", OmitEmpty: true},
+			{JSON: "target_type", Name: "TargetType", OmitEmpty: true},
+			{JSON: "repository_selection", Name: "RepositorySelection", OmitEmpty: true, Comment: `"all" or "selected"`},
+			{JSON: "permissions", Name: "Permissions", Type: "map[string]string", OmitEmpty: true},
+			{JSON: "events", Name: "Events", OmitEmpty: true},
+			// The schema allows Unix seconds as well; GitHub sends RFC 3339.
+			{JSON: "created_at", Name: "CreatedAt", Type: "*time.Time", OmitEmpty: true},
+			{JSON: "updated_at", Name: "UpdatedAt", Type: "*time.Time", OmitEmpty: true},
+			{JSON: "suspended_at", Name: "SuspendedAt", Type: "*time.Time", OmitEmpty: true},
+			{JSON: "suspended_by", Name: "SuspendedBy", Type: "*User", OmitEmpty: true},
+		},
+	},
+	{
+		Name:   "InstallationRepository",
+		Doc:    "InstallationRepository is a repository listed in installation payloads.",
+		Source: "installation$created/repositories/[]",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "full_name", Name: "FullName"},
+			{JSON: "private", Name: "Private"},
+		},
+	},
+	{
+		Name:     "PingEvent",
+		Doc:      "PingEvent is sent when a webhook is created.",
+		Source:   "ping$event",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "zen", Name: "Zen"},
+			{JSON: "hook_id", Name: "HookID"},
+			{JSON: "hook", Name: "Hook", Type: "*PingHook", OmitEmpty: true},
+		},
+	},
+	{
+		Name:   "PingHook",
+		Doc:    "PingHook is the webhook described by a PingEvent.",
+		Source: "ping$event/hook",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "type", Name: "Type", Comment: `"Repository", "Organization", "App", ...`},
+			{JSON: "name", Name: "Name"},
+			{JSON: "active", Name: "Active"},
+			{JSON: "events", Name: "Events"},
+			{JSON: "app_id", Name: "AppID", OmitEmpty: true},
+		},
+	},
+	{
+		Name: "InstallationEvent",
+		Doc: "InstallationEvent is sent when a GitHub App is installed, uninstalled,\n" +
+			"suspended, unsuspended, or its permissions are accepted. Actions:\n" +
+			`"created", "deleted", "suspend", "unsuspend", "new_permissions_accepted".`,
+		Source:   "installation$created",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{
+				JSON: "repositories", Name: "Repositories", Type: "[]InstallationRepository", OmitEmpty: true,
+				Doc: "Repositories are the repositories the installation can access, for\n" +
+					`the "created" and "deleted" actions.`,
+			},
+			{JSON: "requester", Name: "Requester", Type: "*User", OmitEmpty: true},
+		},
+	},
+	{
+		Name: "InstallationRepositoriesEvent",
+		Doc: "InstallationRepositoriesEvent is sent when repositories are added to or\n" +
+			`removed from an installation. Actions: "added", "removed".`,
+		Source:   "installation_repositories$added",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "repository_selection", Name: "RepositorySelection"},
+			{JSON: "repositories_added", Name: "RepositoriesAdded", Type: "[]InstallationRepository"},
+			{JSON: "repositories_removed", Name: "RepositoriesRemoved", Type: "[]InstallationRepository"},
+			{JSON: "requester", Name: "Requester", Type: "*User", OmitEmpty: true},
+		},
+	},
+	{
+		Name:     "PushEvent",
+		Doc:      "PushEvent is sent when commits or tags are pushed.",
+		Source:   "push$event",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "ref", Name: "Ref"},
+			{JSON: "before", Name: "Before"},
+			{JSON: "after", Name: "After"},
+			{JSON: "created", Name: "Created"},
+			{JSON: "deleted", Name: "Deleted"},
+			{JSON: "forced", Name: "Forced"},
+			{JSON: "base_ref", Name: "BaseRef"},
+			{JSON: "compare", Name: "Compare"},
+			{JSON: "commits", Name: "Commits"},
+			{JSON: "head_commit", Name: "HeadCommit"},
+			{JSON: "pusher", Name: "Pusher", OmitEmpty: true},
+		},
+	},
+	{
+		Name:   "Commit",
+		Doc:    "Commit is a commit in a PushEvent.",
+		Source: "commit",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "tree_id", Name: "TreeID"},
+			{JSON: "distinct", Name: "Distinct"},
+			{JSON: "message", Name: "Message"},
+			{JSON: "timestamp", Name: "Timestamp"},
+			{JSON: "url", Name: "URL"},
+			{JSON: "author", Name: "Author", OmitEmpty: true},
+			{JSON: "committer", Name: "Committer", OmitEmpty: true},
+			{JSON: "added", Name: "Added"},
+			{JSON: "removed", Name: "Removed"},
+			{JSON: "modified", Name: "Modified"},
+		},
+	},
+	{
+		Name:   "CommitAuthor",
+		Doc:    "CommitAuthor is the git author, committer or pusher of a push.",
+		Source: "committer",
+		Fields: []fieldSpec{
+			{JSON: "name", Name: "Name"},
+			{JSON: "email", Name: "Email", Type: "string"},
+			{JSON: "username", Name: "Username", OmitEmpty: true},
+		},
+	},
+	{
+		Name: "PullRequestEvent",
+		Doc: "PullRequestEvent is sent for pull request activity, such as \"opened\",\n" +
+			`"closed", "reopened", "synchronize", "edited" or "labeled".`,
+		Source:   "pull_request$opened",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "number", Name: "Number", Type: "int"},
+			{JSON: "pull_request", Name: "PullRequest", Type: "*PullRequest"},
+		},
+	},
+	{
+		Name:   "PullRequest",
+		Doc:    "PullRequest is a pull request.",
+		Source: "pull-request",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "number", Name: "Number", Type: "int"},
+			{JSON: "state", Name: "State", Type: "string", Comment: `"open" or "closed"`},
+			{JSON: "title", Name: "Title"},
+			{JSON: "body", Name: "Body"},
+			{JSON: "user", Name: "User"},
+			{JSON: "draft", Name: "Draft"},
+			{JSON: "merged", Name: "Merged", Type: "bool"},
+			{JSON: "merge_commit_sha", Name: "MergeCommitSHA"},
+			{JSON: "html_url", Name: "HTMLURL"},
+			{JSON: "head", Name: "Head", Type: "*PRBranch"},
+			{JSON: "base", Name: "Base", Type: "*PRBranch"},
+			{JSON: "labels", Name: "Labels"},
+			{JSON: "created_at", Name: "CreatedAt"},
+			{JSON: "updated_at", Name: "UpdatedAt"},
+			{JSON: "closed_at", Name: "ClosedAt"},
+			{JSON: "merged_at", Name: "MergedAt"},
+		},
+	},
+	{
+		Name:   "PRBranch",
+		Doc:    "PRBranch is the head or base of a pull request.",
+		Source: "pull-request/head",
+		Fields: []fieldSpec{
+			{JSON: "label", Name: "Label"},
+			{JSON: "ref", Name: "Ref"},
+			{JSON: "sha", Name: "SHA"},
+			{JSON: "user", Name: "User"},
+			{JSON: "repo", Name: "Repo"},
+		},
+	},
+	{
+		Name:   "Label",
+		Doc:    "Label is an issue or pull request label.",
+		Source: "label",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "color", Name: "Color"},
+		},
+	},
+	{
+		Name: "IssuesEvent",
+		Doc: "IssuesEvent is sent for issue activity, such as \"opened\", \"closed\",\n" +
+			`"edited", "labeled" or "assigned".`,
+		Source:   "issues$opened",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "issue", Name: "Issue", Type: "*Issue"},
+		},
+	},
+	{
+		Name:   "Issue",
+		Doc:    "Issue is an issue.",
+		Source: "issue",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "number", Name: "Number", Type: "int"},
+			{JSON: "title", Name: "Title"},
+			{JSON: "body", Name: "Body"},
+			{JSON: "state", Name: "State", Type: "string", Comment: `"open" or "closed"`},
+			{JSON: "user", Name: "User"},
+			{JSON: "labels", Name: "Labels"},
+			{JSON: "assignees", Name: "Assignees"},
+			{JSON: "html_url", Name: "HTMLURL"},
+			{JSON: "created_at", Name: "CreatedAt"},
+			{JSON: "updated_at", Name: "UpdatedAt"},
+			{JSON: "closed_at", Name: "ClosedAt"},
+		},
+	},
+	{
+		Name:   "App",
+		Doc:    "App is the GitHub App that owns a check run or check suite.",
+		Source: "app",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "slug", Name: "Slug", Type: "string"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+		},
+	},
+	{
+		Name:   "PullRequestRef",
+		Doc:    "PullRequestRef is a pull request referenced by checks and workflow runs.",
+		Source: "check-run-pull-request",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "number", Name: "Number", Type: "int"},
+			{JSON: "url", Name: "URL"},
+			{JSON: "head", Name: "Head", Type: "struct {\nRef string `json:\"ref\"`\nSHA string `json:\"sha\"`\n}"},
+			{JSON: "base", Name: "Base", Type: "struct {\nRef string `json:\"ref\"`\nSHA string `json:\"sha\"`\n}"},
+		},
+	},
+	{
+		Name: "CheckRunEvent",
+		Doc: "CheckRunEvent is sent for check run activity. Actions: \"created\",\n" +
+			`"completed", "rerequested", "requested_action".`,
+		Source:   "check_run$requested_action",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "check_run", Name: "CheckRun", Type: "*CheckRun"},
+			{
+				JSON: "requested_action", Name: "RequestedAction", OmitEmpty: true,
+				Type: "*struct {\nIdentifier string `json:\"identifier\"`\n}",
+				Doc:  `RequestedAction is set for the "requested_action" action.`,
+			},
+		},
+	},
+	{
+		Name:   "CheckRun",
+		Doc:    "CheckRun is a check run.",
+		Source: "check_run$completed/check_run",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "head_sha", Name: "HeadSHA"},
+			{JSON: "external_id", Name: "ExternalID"},
+			{JSON: "status", Name: "Status", Type: "string", Comment: `"queued", "in_progress", "completed", ...`},
+			{JSON: "conclusion", Name: "Conclusion", Type: "*string", Comment: "set once completed"},
+			{JSON: "html_url", Name: "HTMLURL"},
+			{JSON: "details_url", Name: "DetailsURL"},
+			{JSON: "started_at", Name: "StartedAt", Type: "*time.Time"},
+			{JSON: "completed_at", Name: "CompletedAt"},
+			{JSON: "check_suite", Name: "CheckSuite", Type: "*CheckSuite"},
+			{JSON: "app", Name: "App"},
+			{JSON: "pull_requests", Name: "PullRequests", Type: "[]PullRequestRef"},
+		},
+	},
+	{
+		Name: "CheckSuiteEvent",
+		Doc: "CheckSuiteEvent is sent for check suite activity. Actions: \"completed\",\n" +
+			`"requested", "rerequested".`,
+		Source:   "check_suite$completed",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "check_suite", Name: "CheckSuite", Type: "*CheckSuite"},
+		},
+	},
+	{
+		Name:   "CheckSuite",
+		Doc:    "CheckSuite is a check suite.",
+		Source: "check_suite$completed/check_suite",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "head_branch", Name: "HeadBranch"},
+			{JSON: "head_sha", Name: "HeadSHA"},
+			{JSON: "status", Name: "Status", Type: "*string"},
+			{JSON: "conclusion", Name: "Conclusion", Type: "*string"},
+			{JSON: "before", Name: "Before"},
+			{JSON: "after", Name: "After"},
+			{JSON: "app", Name: "App"},
+			{JSON: "pull_requests", Name: "PullRequests", Type: "[]PullRequestRef"},
+			{JSON: "latest_check_runs_count", Name: "LatestCheckRunsCount", Type: "int"},
+			{JSON: "created_at", Name: "CreatedAt", Type: "*time.Time"},
+			{JSON: "updated_at", Name: "UpdatedAt", Type: "*time.Time"},
+		},
+	},
+	{
+		Name: "WorkflowRunEvent",
+		Doc: "WorkflowRunEvent is sent for GitHub Actions workflow run activity.\n" +
+			`Actions: "requested", "in_progress", "completed".`,
+		Source:   "workflow_run$completed",
+		Envelope: true,
+		Fields: []fieldSpec{
+			{JSON: "workflow", Name: "Workflow"},
+			{JSON: "workflow_run", Name: "WorkflowRun", Type: "*WorkflowRun"},
+		},
+	},
+	{
+		Name:   "Workflow",
+		Doc:    "Workflow is a GitHub Actions workflow.",
+		Source: "workflow",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "path", Name: "Path"},
+			{JSON: "state", Name: "State"},
+		},
+	},
+	{
+		Name:   "WorkflowRun",
+		Doc:    "WorkflowRun is a GitHub Actions workflow run.",
+		Source: "workflow-run",
+		Fields: []fieldSpec{
+			{JSON: "id", Name: "ID"},
+			{JSON: "node_id", Name: "NodeID"},
+			{JSON: "name", Name: "Name"},
+			{JSON: "workflow_id", Name: "WorkflowID"},
+			{JSON: "head_branch", Name: "HeadBranch"},
+			{JSON: "head_sha", Name: "HeadSHA"},
+			{JSON: "path", Name: "Path"},
+			{JSON: "run_number", Name: "RunNumber", Type: "int"},
+			{JSON: "run_attempt", Name: "RunAttempt", Type: "int"},
+			{JSON: "event", Name: "Event"},
+			{JSON: "status", Name: "Status", Type: "*string"},
+			{JSON: "conclusion", Name: "Conclusion", Type: "*string"},
+			{JSON: "html_url", Name: "HTMLURL"},
+			{JSON: "actor", Name: "Actor"},
+			{JSON: "triggering_actor", Name: "TriggeringActor"},
+			{JSON: "pull_requests", Name: "PullRequests", Type: "[]PullRequestRef"},
+			{JSON: "created_at", Name: "CreatedAt"},
+			{JSON: "updated_at", Name: "UpdatedAt"},
+			{JSON: "run_started_at", Name: "RunStartedAt"},
+		},
+	},
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Invalidator drops cached state about a GitHub App installation, such as
+// its installation tokens. *githubauth.InstallationCache implements it.
+// It also forgets the installation IDs the cache looked up. Caches kept by
+// the application can implement Invalidator too, or be adapted with
+// InvalidatorFunc.
+type Invalidator interface {
+	Invalidate(ctx context.Context, installationID int64) error
+}
+
+// InvalidatorFunc adapts a function to an Invalidator.
+type InvalidatorFunc func(ctx context.Context, installationID int64) error
+
+// Invalidate calls f.
+func (f InvalidatorFunc) Invalidate(ctx context.Context, installationID int64) error {
+	return f(ctx, installationID)
+}
+
+// invalidatingEvents are the events after which cached installation state
+// is stale: the installation event covers "created", "deleted", "suspend",
+// "unsuspend" and "new_permissions_accepted"; installation_repositories
+// covers repositories being added or removed.
+var invalidatingEvents = map[string]bool{
+	"installation":              true,
+	"installation_repositories": true,
+}
+
+// InvalidateInstallation calls every invalidator for the installation of a
+// delivery of event that changes what the installation's tokens grant: any
+// installation or installation_repositories event. It reports whether the
+// delivery was such an event. All invalidators are called even if some fail;
+// their errors are joined.
+func InvalidateInstallation(ctx context.Context, event string, payload []byte, invalidators ...Invalidator) (bool, error) {
+	if !invalidatingEvents[event] {
+		return false, nil
+	}
+	env, err := DecodeEnvelope(payload)
+	if err != nil {
+		return false, err
+	}
+	if env.Installation == nil || env.Installation.ID == 0 {
+		return false, ErrNoInstallation
+	}
+
+	return true, invalidate(ctx, env.Installation.ID, invalidators)
+}
+
+// invalidate calls every invalidator for installation id.
+func invalidate(ctx context.Context, id int64, invalidators []Invalidator) error {
+	var errs []error
+	for _, inv := range invalidators {
+		if err := inv.Invalidate(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to invalidate installation %d: %w", id, err)
+	}
+	return nil
+}
+
+// InvalidationHandler returns an http.Handler that invalidates cached
+// installation state with InvalidateInstallation. Use it when the
+// installation events need no other handling:
+//
+//	installations := githubauth.NewInstallationCache(appSrc)
+//	invalidate := webhook.InvalidationHandler(installations)
+//	router.On("installation", "", invalidate)
+//	router.On("installation_repositories", "", invalidate)
+//
+// A Router dispatches each delivery to a single handler, so routing the same
+// events to a handler of your own bypasses it; wrap with InvalidateThen
+// instead.
+//
+// It answers 204 No Content once the caches are invalidated, 202 Accepted
+// for other events, 400 Bad Request for malformed payloads and 500 Internal
+// Server Error when an invalidator fails, so GitHub reports the delivery as
+// failed and it can be redelivered.
+func InvalidationHandler(invalidators ...Invalidator) http.Handler {
+	return InvalidateThen(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.Header.Get(EventHeader)
+		if ev, ok := EventFromContext(r.Context()); ok {
+			event = ev.Name
+		}
+		if invalidatingEvents[event] {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}), invalidators...)
+}
+
+// InvalidateThen returns an http.Handler that invalidates cached
+// installation state with InvalidateInstallation and then calls next, so
+// installation events reach your own handlers with fresh caches. Wrap a
+// Router, or a single handler:
+//
+//	installations := githubauth.NewInstallationCache(appSrc)
+//	router.On("installation", "created", onInstalled)
+//	handler := webhook.Middleware(secret)(webhook.InvalidateThen(router, installations))
+//
+// Other events are passed to next untouched. A malformed payload or one
+// without an installation is answered with 400 Bad Request, and a failing
+// invalidator with 500 Internal Server Error, without calling next.
+func InvalidateThen(next http.Handler, invalidators ...Invalidator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.Header.Get(EventHeader)
+		if ev, ok := EventFromContext(r.Context()); ok {
+			event = ev.Name
+		}
+		if !invalidatingEvents[event] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		payload, err := requestPayload(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		env, err := DecodeEnvelope(payload)
+		if err != nil {
+			http.Error(w, "malformed payload", http.StatusBadRequest)
+			return
+		}
+		if env.Installation == nil || env.Installation.ID == 0 {
+			http.Error(w, ErrNoInstallation.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := invalidate(r.Context(), env.Installation.ID, invalidators); err != nil {
+			http.Error(w, "failed to invalidate installation", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInvalidationHandler(t *testing.T) {
+	failing := InvalidatorFunc(func(context.Context, int64) error { return errors.New("store down") })
+
+	tests := []struct {
+		name     string
+		event    string
+		body     string
+		fail     bool
+		wantCode int
+		wantIDs  []int64
+	}{
+		{name: "installation", event: "installation", body: string(readFixture(t, "installation")), wantCode: http.StatusNoContent, wantIDs: []int64{2, 2}},
+		{name: "repositories", event: "installation_repositories", body: string(readFixture(t, "installation_repositories")), wantCode: http.StatusNoContent, wantIDs: []int64{2, 2}},
+		{name: "new permissions", event: "installation", body: `{"action":"new_permissions_accepted","installation":{"id":9}}`, wantCode: http.StatusNoContent, wantIDs: []int64{9, 9}},
+		{name: "other event", event: "push", body: string(readFixture(t, "push")), wantCode: http.StatusAccepted},
+		{name: "no installation", event: "installation", body: `{"action":"deleted"}`, wantCode: http.StatusBadRequest},
+		{name: "malformed", event: "installation", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "invalidator fails", event: "installation", body: `{"action":"suspend","installation":{"id":9}}`, fail: true, wantCode: http.StatusInternalServerError, wantIDs: []int64{9, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			record := InvalidatorFunc(func(_ context.Context, id int64) error {
+				got = append(got, id)
+				return nil
+			})
+			invalidators := []Invalidator{record, record}
+			if tt.fail {
+				// A failing invalidator does not stop the others.
+				invalidators = []Invalidator{record, failing, record}
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set(EventHeader, tt.event)
+			rec := httptest.NewRecorder()
+			InvalidationHandler(invalidators...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("invalidated %v, want %v", got, tt.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tt.wantIDs[i] {
+					t.Errorf("invalidated %v, want %v", got, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidateInstallation(t *testing.T) {
+	ctx := context.Background()
+	var got int64
+	inv := InvalidatorFunc(func(_ context.Context, id int64) error {
+		got = id
+		return nil
+	})
+
+	if ok, err := InvalidateInstallation(ctx, "installation", readFixture(t, "installation"), inv); !ok || err != nil || got != 2 {
+		t.Errorf("InvalidateInstallation(installation) = %v, %v; invalidated %d", ok, err, got)
+	}
+	if ok, err := InvalidateInstallation(ctx, "issues", readFixture(t, "issues"), inv); ok || err != nil {
+		t.Errorf("InvalidateInstallation(issues) = %v, %v", ok, err)
+	}
+	if _, err := InvalidateInstallation(ctx, "installation", []byte(`{}`), inv); !errors.Is(err, ErrNoInstallation) {
+		t.Errorf("InvalidateInstallation(no installation) error = %v, want ErrNoInstallation", err)
+	}
+}
+
+func TestInvalidateThen(t *testing.T) {
+	var invalidated []int64
+	inv := InvalidatorFunc(func(_ context.Context, id int64) error {
+		invalidated = append(invalidated, id)
+		return nil
+	})
+
+	var handled []string
+	router := NewRouter()
+	router.OnFunc("installation", "created", func(w http.ResponseWriter, r *http.Request) {
+		if len(invalidated) == 0 {
+			t.Error("handler called before the caches were invalidated")
+		}
+		ev, _ := EventFromContext(r.Context())
+		handled = append(handled, ev.Name+"."+ev.Action)
+		w.WriteHeader(http.StatusOK)
+	})
+	router.OnFunc("push", "", func(w http.ResponseWriter, _ *http.Request) {
+		handled = append(handled, "push")
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := InvalidateThen(router, inv)
+
+	tests := []struct {
+		name     string
+		event    string
+		body     string
+		wantCode int
+		wantIDs  int
+		wantCall string
+	}{
+		{name: "routed installation event", event: "installation", body: `{"action":"created","installation":{"id":9}}`, wantCode: http.StatusOK, wantIDs: 1, wantCall: "installation.created"},
+		{name: "other event", event: "push", body: string(readFixture(t, "push")), wantCode: http.StatusOK, wantCall: "push"},
+		{name: "no installation", event: "installation", body: `{"action":"created"}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidated, handled = nil, nil
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set(EventHeader, tt.event)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if len(invalidated) != tt.wantIDs {
+				t.Errorf("invalidated %v, want %d installations", invalidated, tt.wantIDs)
+			}
+			var want []string
+			if tt.wantCall != "" {
+				want = []string{tt.wantCall}
+			}
+			if strings.Join(handled, ",") != strings.Join(want, ",") {
+				t.Errorf("handled %v, want %v", handled, want)
+			}
+		})
+	}
+}
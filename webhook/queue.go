@@ -0,0 +1,496 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jferrl/go-githubauth/internal/atomicfile"
+)
+
+// Defaults used by NewQueue.
+const (
+	DefaultQueueWorkers     = 4
+	DefaultQueueMaxAttempts = 5
+	DefaultQueueBackoff     = time.Second
+	DefaultQueueMaxBackoff  = 5 * time.Minute
+)
+
+// QueuedDelivery is a delivery persisted by Queue and handed to its
+// ProcessFunc.
+type QueuedDelivery struct {
+	// Delivery is the metadata of the delivery.
+	Delivery Delivery `json:"delivery"`
+	// Payload is the JSON payload, also for deliveries sent as
+	// application/x-www-form-urlencoded.
+	Payload json.RawMessage `json:"payload"`
+	// Repository is the payload's repository.full_name, empty for events
+	// without a repository. Deliveries of one repository are processed in
+	// order.
+	Repository string `json:"repository,omitempty"`
+	// Attempts is the number of earlier failed attempts.
+	Attempts int `json:"attempts"`
+	// EnqueuedAt is when the delivery was persisted.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// ProcessFunc processes a queued delivery. A returned error (or a panic)
+// schedules a retry.
+type ProcessFunc func(ctx context.Context, d *QueuedDelivery) error
+
+// QueueOpt is a functional option for NewQueue.
+type QueueOpt func(*Queue)
+
+// WithQueueWorkers sets how many deliveries are processed concurrently.
+// Defaults to DefaultQueueWorkers.
+func WithQueueWorkers(n int) QueueOpt {
+	return func(q *Queue) {
+		if n > 0 {
+			q.workers = n
+		}
+	}
+}
+
+// WithQueueMaxAttempts sets how many times a delivery is attempted before it
+// is moved to the dead-letter directory. Defaults to
+// DefaultQueueMaxAttempts.
+func WithQueueMaxAttempts(n int) QueueOpt {
+	return func(q *Queue) {
+		if n > 0 {
+			q.maxAttempts = n
+		}
+	}
+}
+
+// WithQueueBackoff sets the delay before the first retry, doubled for every
+// further retry up to maxDelay. Defaults to DefaultQueueBackoff and
+// DefaultQueueMaxBackoff.
+func WithQueueBackoff(delay, maxDelay time.Duration) QueueOpt {
+	return func(q *Queue) {
+		if delay > 0 {
+			q.backoff = delay
+		}
+		if maxDelay > 0 {
+			q.maxBackoff = maxDelay
+		}
+	}
+}
+
+// WithQueueErrorHandler sets a function called for every failed attempt and
+// every failure to update the queue directory, e.g. to log them. d is nil
+// for failures not tied to a delivery. fn is called from the queue's
+// goroutines and must not block.
+func WithQueueErrorHandler(fn func(d *QueuedDelivery, err error)) QueueOpt {
+	return func(q *Queue) { q.onError = fn }
+}
+
+// Queue acknowledges webhook deliveries immediately and processes them in
+// the background, so slow handlers do not run into GitHub's 10 second
+// delivery timeout. As an http.Handler it persists each delivery to a
+// directory and responds 202 Accepted; Run processes persisted deliveries
+// with a pool of workers. Mount it behind Middleware so only verified
+// deliveries are queued:
+//
+//	q, err := webhook.NewQueue("/var/lib/app/webhooks", process)
+//	go q.Run(ctx)
+//	http.Handle("/webhook", webhook.Middleware(secret)(q))
+//
+// Deliveries survive restarts: Run picks up everything left in the
+// directory. A failed delivery is retried with exponential backoff and moved
+// to the "dead" subdirectory after the last attempt, together with its last
+// error. Deliveries of the same repository are processed one at a time in
+// the order they were received, so a failing delivery holds back later ones
+// of its repository until it succeeds or is dead-lettered; deliveries
+// without a repository are processed in parallel.
+//
+// Processing is at least once: a delivery interrupted by a crash is
+// processed again. Combine with Deduplicate to drop GitHub's redeliveries
+// before they are queued.
+type Queue struct {
+	pendingDir string
+	deadDir    string
+	process    ProcessFunc
+
+	workers     int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	onError     func(*QueuedDelivery, error)
+
+	lastSeq atomic.Int64
+	running atomic.Bool
+
+	mu    sync.Mutex
+	inbox []*queueItem
+	wake  chan struct{}
+}
+
+// queueRecord is the content of a queue file.
+type queueRecord struct {
+	QueuedDelivery
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type queueItem struct {
+	name string
+	rec  queueRecord
+}
+
+// NewQueue returns a Queue persisting deliveries under dir, creating it
+// with mode 0700 if needed, and processing them with process once Run is
+// called.
+func NewQueue(dir string, process ProcessFunc, opts ...QueueOpt) (*Queue, error) {
+	if dir == "" {
+		return nil, errors.New("queue directory is required")
+	}
+	if process == nil {
+		return nil, errors.New("process function is required")
+	}
+	q := &Queue{
+		pendingDir:  filepath.Join(dir, "pending"),
+		deadDir:     filepath.Join(dir, "dead"),
+		process:     process,
+		workers:     DefaultQueueWorkers,
+		maxAttempts: DefaultQueueMaxAttempts,
+		backoff:     DefaultQueueBackoff,
+		maxBackoff:  DefaultQueueMaxBackoff,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	for _, d := range []string{q.pendingDir, q.deadDir} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory: %w", err)
+		}
+	}
+	return q, nil
+}
+
+// ServeHTTP persists the delivery and responds 202 Accepted. Malformed
+// payloads get 400 Bad Request, and deliveries that cannot be persisted 500
+// Internal Server Error so GitHub reports them as failed.
+func (q *Queue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	payload, err := jsonPayload(r.Header.Get("Content-Type"), body)
+	if err != nil || !json.Valid(payload) {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+	d, ok := DeliveryFromContext(r.Context())
+	if !ok {
+		d = newDelivery(r, 0, SecretSourceFallback, len(body))
+	}
+
+	item := &queueItem{
+		name: q.nextName(d.ID),
+		rec: queueRecord{QueuedDelivery: QueuedDelivery{
+			Delivery:   d,
+			Payload:    payload,
+			Repository: repositoryName(payload),
+			EnqueuedAt: time.Now(),
+		}},
+	}
+	if err := q.write(q.pendingDir, item); err != nil {
+		q.reportError(nil, err)
+		http.Error(w, "failed to queue delivery", http.StatusInternalServerError)
+		return
+	}
+
+	q.mu.Lock()
+	q.inbox = append(q.inbox, item)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// nextName returns a file name for a new delivery. Names sort in the order
+// deliveries were received, also across restarts.
+func (q *Queue) nextName(deliveryID string) string {
+	seq := time.Now().UnixNano()
+	for {
+		last := q.lastSeq.Load()
+		if seq <= last {
+			seq = last + 1
+		}
+		if q.lastSeq.CompareAndSwap(last, seq) {
+			break
+		}
+	}
+	sum := sha256.Sum256([]byte(deliveryID))
+	return fmt.Sprintf("%020d-%s.json", seq, hex.EncodeToString(sum[:8]))
+}
+
+// repositoryName returns the repository.full_name of payload, if any.
+func repositoryName(payload []byte) string {
+	var fields struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	_ = json.Unmarshal(payload, &fields)
+	return fields.Repository.FullName
+}
+
+// lane is the ordered backlog of one repository, or of a single delivery
+// without one.
+type lane struct {
+	items []*queueItem
+	busy  bool
+}
+
+type queueResult struct {
+	item *queueItem
+	err  error
+}
+
+// Run processes queued deliveries until ctx is done, then waits for
+// in-flight deliveries to return. A delivery interrupted by the shutdown is
+// not counted as an attempt and is processed again by the next Run. Only one
+// Run may be active at a time.
+func (q *Queue) Run(ctx context.Context) error {
+	if !q.running.CompareAndSwap(false, true) {
+		return errors.New("webhook: queue is already running")
+	}
+	defer q.running.Store(false)
+
+	lanes := make(map[string]*lane)
+	known := make(map[string]bool)
+	add := func(it *queueItem) {
+		if known[it.name] {
+			return
+		}
+		known[it.name] = true
+		key := laneKey(it)
+		l := lanes[key]
+		if l == nil {
+			l = &lane{}
+			lanes[key] = l
+		}
+		l.items = append(l.items, it)
+	}
+	pop := func(it *queueItem) {
+		key := laneKey(it)
+		l := lanes[key]
+		l.items, l.busy = l.items[1:], false
+		if len(l.items) == 0 {
+			delete(lanes, key)
+		}
+		delete(known, it.name)
+	}
+
+	// Deliveries queued before Run are both on disk and in the inbox.
+	q.mu.Lock()
+	q.inbox = nil
+	q.mu.Unlock()
+	pending, err := q.load()
+	if err != nil {
+		return err
+	}
+	for _, it := range pending {
+		add(it)
+	}
+
+	work := make(chan *queueItem)
+	results := make(chan queueResult)
+	var wg sync.WaitGroup
+	for range q.workers {
+		wg.Go(func() {
+			for it := range work {
+				results <- queueResult{item: it, err: q.safeProcess(ctx, it)}
+			}
+		})
+	}
+	idle := q.workers
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		// Start every lane head that is due, oldest first.
+		now := time.Now()
+		var ready []*lane
+		var next time.Time
+		for _, l := range lanes {
+			if l.busy {
+				continue
+			}
+			if at := l.items[0].rec.NextAttempt; at.After(now) {
+				if next.IsZero() || at.Before(next) {
+					next = at
+				}
+				continue
+			}
+			ready = append(ready, l)
+		}
+		slices.SortFunc(ready, func(a, b *lane) int { return strings.Compare(a.items[0].name, b.items[0].name) })
+		for _, l := range ready {
+			if idle == 0 {
+				break
+			}
+			l.busy = true
+			idle--
+			work <- l.items[0]
+		}
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+
+		select {
+		case <-ctx.Done():
+			close(work)
+			for idle < q.workers {
+				res := <-results
+				idle++
+				if res.err == nil {
+					q.finish(res.item)
+				}
+			}
+			wg.Wait()
+			return nil
+		case <-q.wake:
+			q.mu.Lock()
+			inbox := q.inbox
+			q.inbox = nil
+			q.mu.Unlock()
+			for _, it := range inbox {
+				// A delivery queued while Run started may have been loaded
+				// from disk and processed already.
+				if _, err := os.Stat(filepath.Join(q.pendingDir, it.name)); err == nil {
+					add(it)
+				}
+			}
+		case res := <-results:
+			idle++
+			switch {
+			case res.err == nil:
+				q.finish(res.item)
+				pop(res.item)
+			case ctx.Err() != nil:
+				// Interrupted by the shutdown; not an attempt.
+				lanes[laneKey(res.item)].busy = false
+			case q.retry(res.item, res.err):
+				lanes[laneKey(res.item)].busy = false
+			default:
+				pop(res.item)
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// laneKey returns the lane of it: its repository, or its own lane.
+func laneKey(it *queueItem) string {
+	if it.rec.Repository == "" {
+		return "\x00" + it.name
+	}
+	return it.rec.Repository
+}
+
+// safeProcess runs the ProcessFunc on a copy of the delivery, turning a
+// panic into an error.
+func (q *Queue) safeProcess(ctx context.Context, it *queueItem) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("webhook: panic processing delivery: %v", r)
+		}
+	}()
+	d := it.rec.QueuedDelivery
+	return q.process(ctx, &d)
+}
+
+// finish removes a processed delivery from the queue directory.
+func (q *Queue) finish(it *queueItem) {
+	if err := os.Remove(filepath.Join(q.pendingDir, it.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		q.reportError(&it.rec.QueuedDelivery, fmt.Errorf("failed to remove processed delivery: %w", err))
+	}
+}
+
+// retry records a failed attempt. It reports whether the delivery stays
+// queued; otherwise it has been moved to the dead-letter directory.
+func (q *Queue) retry(it *queueItem, err error) bool {
+	it.rec.Attempts++
+	it.rec.LastError = err.Error()
+	q.reportError(&it.rec.QueuedDelivery, err)
+
+	if it.rec.Attempts >= q.maxAttempts {
+		if err := q.write(q.deadDir, it); err != nil {
+			q.reportError(&it.rec.QueuedDelivery, err)
+		}
+		q.finish(it)
+		return false
+	}
+
+	delay := q.backoff << (it.rec.Attempts - 1)
+	if delay > q.maxBackoff || delay <= 0 {
+		delay = q.maxBackoff
+	}
+	it.rec.NextAttempt = time.Now().Add(delay)
+	if err := q.write(q.pendingDir, it); err != nil {
+		q.reportError(&it.rec.QueuedDelivery, err)
+	}
+	return true
+}
+
+// load returns the deliveries in the queue directory in the order they were
+// received.
+func (q *Queue) load() ([]*queueItem, error) {
+	entries, err := os.ReadDir(q.pendingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue directory: %w", err)
+	}
+	var items []*queueItem
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(q.pendingDir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queued delivery: %w", err)
+		}
+		it := &queueItem{name: e.Name()}
+		if err := json.Unmarshal(b, &it.rec); err != nil {
+			q.reportError(nil, fmt.Errorf("failed to decode queued delivery %s: %w", e.Name(), err))
+			continue
+		}
+		items = append(items, it)
+	}
+	// os.ReadDir sorts by name, which is the order of receipt.
+	return items, nil
+}
+
+func (q *Queue) write(dir string, it *queueItem) error {
+	b, err := json.Marshal(it.rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode delivery: %w", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(dir, it.name), b, 0o600); err != nil {
+		return fmt.Errorf("failed to persist delivery: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) reportError(d *QueuedDelivery, err error) {
+	if q.onError != nil {
+		q.onError(d, err)
+	}
+}
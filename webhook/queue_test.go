@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// enqueue sends a delivery for repo to h and returns the response status.
+func enqueue(t *testing.T, h http.Handler, id, repo string) int {
+	t.Helper()
+	body := `{"action":"opened","repository":{"full_name":"` + repo + `"}}`
+	if repo == "" {
+		body = `{"zen":"hi"}`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set(EventHeader, "issues")
+	req.Header.Set(DeliveryHeader, id)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// runQueue runs q until the test ends.
+func runQueue(t *testing.T, q *Queue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- q.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+	})
+}
+
+// recorder records processed delivery IDs and fails the configured ones.
+type recorder struct {
+	mu       sync.Mutex
+	order    []string
+	failures map[string]int
+	done     chan string
+}
+
+func newRecorder(failures map[string]int) *recorder {
+	return &recorder{failures: failures, done: make(chan string, 100)}
+}
+
+func (r *recorder) process(_ context.Context, d *QueuedDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, d.Delivery.ID)
+	if r.failures[d.Delivery.ID] > d.Attempts {
+		return errors.New("transient failure")
+	}
+	r.done <- d.Delivery.ID
+	return nil
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-r.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for deliveries")
+		}
+	}
+}
+
+func (r *recorder) processed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+func TestQueue_AcknowledgesAndProcesses(t *testing.T) {
+	dir := t.TempDir()
+	rec := newRecorder(nil)
+	var got *QueuedDelivery
+	q, err := NewQueue(dir, func(ctx context.Context, d *QueuedDelivery) error {
+		got = d
+		return rec.process(ctx, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deliveries are accepted before Run starts.
+	if code := enqueue(t, q, "d1", "octo-org/octo-repo"); code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", code)
+	}
+	runQueue(t, q)
+	rec.wait(t, 1)
+
+	if got.Delivery.Event != "issues" || got.Repository != "octo-org/octo-repo" || got.Attempts != 0 {
+		t.Errorf("processed %+v", got)
+	}
+	var payload struct{ Action string }
+	if err := json.Unmarshal(got.Payload, &payload); err != nil || payload.Action != "opened" {
+		t.Errorf("payload = %s", got.Payload)
+	}
+	waitEmpty(t, filepath.Join(dir, "pending"))
+}
+
+func TestQueue_MalformedPayload(t *testing.T) {
+	q, err := NewQueue(t.TempDir(), func(context.Context, *QueuedDelivery) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{`))
+	rec := httptest.NewRecorder()
+	q.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestQueue_RetriesAndDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	rec := newRecorder(map[string]int{"flaky": 2, "broken": 100})
+	var reported []string
+	var mu sync.Mutex
+	q, err := NewQueue(dir, rec.process,
+		WithQueueMaxAttempts(3),
+		WithQueueBackoff(time.Millisecond, 5*time.Millisecond),
+		WithQueueErrorHandler(func(d *QueuedDelivery, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, d.Delivery.ID)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runQueue(t, q)
+
+	enqueue(t, q, "flaky", "")
+	enqueue(t, q, "broken", "")
+	rec.wait(t, 1) // flaky succeeds on its third attempt
+
+	deadDir := filepath.Join(dir, "dead")
+	var dead []os.DirEntry
+	for deadline := time.Now().Add(5 * time.Second); len(dead) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		dead, _ = os.ReadDir(deadDir)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(dead))
+	}
+	b, err := os.ReadFile(filepath.Join(deadDir, dead[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var letter queueRecord
+	if err := json.Unmarshal(b, &letter); err != nil {
+		t.Fatal(err)
+	}
+	if letter.Delivery.ID != "broken" || letter.Attempts != 3 || letter.LastError != "transient failure" {
+		t.Errorf("dead letter = %+v", letter)
+	}
+	waitEmpty(t, filepath.Join(dir, "pending"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 5 { // 2 flaky failures + 3 broken ones
+		t.Errorf("reported failures = %v, want 5", reported)
+	}
+}
+
+func TestQueue_PerRepositoryOrdering(t *testing.T) {
+	rec := newRecorder(map[string]int{"a1": 1})
+	q, err := NewQueue(t.TempDir(), rec.process,
+		WithQueueWorkers(4),
+		WithQueueBackoff(20*time.Millisecond, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enqueue(t, q, "a1", "org/a")
+	enqueue(t, q, "a2", "org/a")
+	enqueue(t, q, "b1", "org/b")
+	runQueue(t, q)
+	rec.wait(t, 3)
+
+	got := rec.processed()
+	// a1 fails once and holds back a2; b1 is not held back.
+	pos := func(id string) int {
+		for i := len(got) - 1; i >= 0; i-- {
+			if got[i] == id {
+				return i
+			}
+		}
+		return -1
+	}
+	if len(got) != 4 || pos("a1") > pos("a2") || pos("b1") > pos("a1") {
+		t.Errorf("processing order = %v, want a1 (retried) before a2, b1 not delayed", got)
+	}
+}
+
+func TestQueue_ResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewQueue(dir, func(context.Context, *QueuedDelivery) error {
+		t.Error("first queue processed a delivery")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, first, "d1", "org/a")
+	enqueue(t, first, "d2", "org/a")
+
+	rec := newRecorder(nil)
+	second, err := NewQueue(dir, rec.process)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runQueue(t, second)
+	rec.wait(t, 2)
+
+	if got := rec.processed(); len(got) != 2 || got[0] != "d1" || got[1] != "d2" {
+		t.Errorf("processed %v, want [d1 d2]", got)
+	}
+}
+
+func TestQueue_RunTwice(t *testing.T) {
+	q, err := NewQueue(t.TempDir(), func(context.Context, *QueuedDelivery) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	runQueue(t, q)
+	for deadline := time.Now().Add(5 * time.Second); !q.running.Load() && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if err := q.Run(context.Background()); err == nil {
+		t.Error("second Run() succeeded")
+	}
+}
+
+func waitEmpty(t *testing.T, dir string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if entries, _ := os.ReadDir(dir); len(entries) == 0 {
+			return
+		}
+	}
+	t.Errorf("%s not empty", dir)
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Event is a delivery dispatched by Router, available to handlers through
+// EventFromContext.
+type Event struct {
+	// Name is the event type from the X-GitHub-Event header, such as
+	// "pull_request".
+	Name string
+	// Action is the payload's "action" field, such as "opened". It is empty
+	// for events without actions, such as push.
+	Action string
+	// Payload is the JSON payload, also for deliveries sent as
+	// application/x-www-form-urlencoded.
+	Payload json.RawMessage
+}
+
+type eventKey struct{}
+
+// EventFromContext returns the Event a Router dispatched the request for.
+func EventFromContext(ctx context.Context) (Event, bool) {
+	ev, ok := ctx.Value(eventKey{}).(Event)
+	return ev, ok
+}
+
+type routeKey struct {
+	event, action string
+}
+
+// Router is an http.Handler dispatching verified deliveries by event type and
+// action, replacing the usual switch on X-GitHub-Event and payload.action.
+// Mount it behind Middleware, which authenticates the body:
+//
+//	router := webhook.NewRouter()
+//	router.On("pull_request", "opened", prOpened)
+//	router.On("push", "", push)
+//	http.Handle("/webhook", webhook.Middleware(secret)(router))
+//
+// A delivery goes to the handler registered for its event and action, else
+// to the one registered for its event with any action, else to the fallback.
+// Deliveries nobody handles are acknowledged with 202 Accepted so GitHub does
+// not report them as failed. Deliveries without an X-GitHub-Event header or
+// with a malformed payload get 400 Bad Request.
+//
+// The payload is parsed once; handlers read it from EventFromContext or from
+// the restored request body. Router is safe for concurrent use.
+type Router struct {
+	mu       sync.RWMutex
+	routes   map[routeKey]http.Handler
+	fallback http.Handler
+}
+
+// NewRouter returns a Router with no routes.
+func NewRouter() *Router {
+	return &Router{routes: make(map[routeKey]http.Handler)}
+}
+
+// On registers h for deliveries of event with the given action. An empty
+// action matches every action of event that has no handler of its own, and
+// is the way to register events without actions, such as push. On panics if
+// event is empty, h is nil or the route is already registered.
+func (rt *Router) On(event, action string, h http.Handler) {
+	if event == "" {
+		panic("webhook: Router.On with empty event")
+	}
+	if h == nil {
+		panic("webhook: Router.On with nil handler")
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	key := routeKey{event: event, action: action}
+	if _, ok := rt.routes[key]; ok {
+		panic(fmt.Sprintf("webhook: Router.On: multiple registrations for %q action %q", event, action))
+	}
+	rt.routes[key] = h
+}
+
+// OnFunc registers the handler function h like On.
+func (rt *Router) OnFunc(event, action string, h func(http.ResponseWriter, *http.Request)) {
+	rt.On(event, action, http.HandlerFunc(h))
+}
+
+// Fallback sets the handler for deliveries no route matches, replacing the
+// default 202 Accepted response.
+func (rt *Router) Fallback(h http.Handler) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.fallback = h
+}
+
+// ServeHTTP dispatches the delivery.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(EventHeader)
+	if name == "" {
+		http.Error(w, "missing "+EventHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	payload, err := jsonPayload(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var fields struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+
+	ev := Event{Name: name, Action: fields.Action, Payload: payload}
+	r = r.WithContext(context.WithValue(r.Context(), eventKey{}, ev))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if h := rt.handler(ev); h != nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handler returns the handler for ev, or nil.
+func (rt *Router) handler(ev Event) http.Handler {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	if h, ok := rt.routes[routeKey{event: ev.Name, action: ev.Action}]; ok {
+		return h
+	}
+	if h, ok := rt.routes[routeKey{event: ev.Name}]; ok {
+		return h
+	}
+	return rt.fallback
+}
+
+// jsonPayload returns the JSON payload of a delivery body, which GitHub sends
+// either as is or in the "payload" field of a form, depending on the content
+// type configured on the webhook.
+func jsonPayload(contentType string, body []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/x-www-form-urlencoded" {
+		return body, nil
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("malformed form payload: %w", err)
+	}
+	if !form.Has("payload") {
+		return nil, errors.New("form payload lacks a payload field")
+	}
+	return []byte(form.Get("payload")), nil
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	var got string
+	route := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ev, ok := EventFromContext(r.Context())
+			if !ok {
+				t.Error("EventFromContext() not ok")
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) == "" || string(ev.Payload) == "" {
+				t.Error("payload not passed on")
+			}
+			got = name + ":" + ev.Name + "/" + ev.Action
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+
+	router := NewRouter()
+	router.OnFunc("pull_request", "opened", route("opened"))
+	router.OnFunc("pull_request", "", route("any"))
+	router.OnFunc("push", "", route("push"))
+
+	tests := []struct {
+		name        string
+		event       string
+		contentType string
+		body        string
+		wantStatus  int
+		wantRoute   string
+	}{
+		{"exact action", "pull_request", "application/json", `{"action":"opened"}`, http.StatusNoContent, "opened:pull_request/opened"},
+		{"any action", "pull_request", "application/json", `{"action":"closed"}`, http.StatusNoContent, "any:pull_request/closed"},
+		{"event without action", "push", "application/json", `{"ref":"refs/heads/main"}`, http.StatusNoContent, "push:push/"},
+		{"form payload", "pull_request", "application/x-www-form-urlencoded",
+			"payload=" + url.QueryEscape(`{"action":"opened"}`), http.StatusNoContent, "opened:pull_request/opened"},
+		{"unhandled event", "issues", "application/json", `{"action":"opened"}`, http.StatusAccepted, ""},
+		{"missing event header", "", "application/json", `{}`, http.StatusBadRequest, ""},
+		{"malformed JSON", "push", "application/json", `{`, http.StatusBadRequest, ""},
+		{"form without payload", "push", "application/x-www-form-urlencoded", "other=1", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.event != "" {
+				req.Header.Set(EventHeader, tt.event)
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || got != tt.wantRoute {
+				t.Errorf("ServeHTTP() status = %d, route = %q, want %d, %q", rec.Code, got, tt.wantStatus, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func TestRouter_Fallback(t *testing.T) {
+	router := NewRouter()
+	router.Fallback(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"zen":"hi"}`))
+	req.Header.Set(EventHeader, "ping")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want fallback's %d", rec.Code, http.StatusTeapot)
+	}
+}
+
+func TestRouter_OnPanics(t *testing.T) {
+	h := http.NotFoundHandler()
+	tests := map[string]func(*Router){
+		"empty event":  func(rt *Router) { rt.On("", "opened", h) },
+		"nil handler":  func(rt *Router) { rt.On("push", "", nil) },
+		"duplicate":    func(rt *Router) { rt.On("push", "", h); rt.On("push", "", h) },
+		"duplicate fn": func(rt *Router) { rt.On("issues", "opened", h); rt.OnFunc("issues", "opened", h.ServeHTTP) },
+	}
+	for name, register := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("On() did not panic")
+				}
+			}()
+			register(NewRouter())
+		})
+	}
+}
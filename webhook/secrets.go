@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+)
+
+// ErrNoSecret is returned when no secret is available to verify a delivery:
+// VerifyAny was given none, or a SecretResolver returned none or failed.
+// Deliveries are rejected rather than accepted unverified.
+var ErrNoSecret = errors.New("webhook: no secret available")
+
+// VerifyAny is Verify for a set of accepted secrets, such as the old and new
+// secret while a rotation rolls out. It returns the index in secrets of the
+// first secret the signature matches. Every secret is tried, so the time
+// taken does not reveal which one matched.
+func VerifyAny(secrets [][]byte, body []byte, signature string) (int, error) {
+	if len(secrets) == 0 {
+		return -1, ErrNoSecret
+	}
+	got, err := parseSignature(signature)
+	if err != nil {
+		return -1, err
+	}
+
+	matched := -1
+	for i, secret := range secrets {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if hmac.Equal(got, mac.Sum(nil)) && matched < 0 {
+			matched = i
+		}
+	}
+	if matched < 0 {
+		return -1, ErrSignatureMismatch
+	}
+	return matched, nil
+}
+
+// SecretResolver returns the secrets accepted for a delivery, most preferred
+// first. It is called for every delivery, so secrets rotated in a secret
+// manager are picked up without a restart; cache inside the resolver if
+// loading is expensive. The request body has not been read yet and must not
+// be.
+type SecretResolver func(r *http.Request) ([][]byte, error)
+
+// StaticSecrets returns a SecretResolver that always accepts secrets. List
+// the new secret first during a rotation, then drop the old one once GitHub
+// has been updated.
+func StaticSecrets(secrets ...[]byte) SecretResolver {
+	return func(*http.Request) ([][]byte, error) { return secrets, nil }
+}
+
+// MiddlewareWithResolver is Middleware for deliveries signed with any of the
+// secrets returned by resolve. The index of the matching secret is available
+// to downstream handlers through MatchedSecret, e.g. to find deliveries still
+// signed with a secret being retired. A failing resolver or one returning no
+// secret rejects the delivery with 500 Internal Server Error, wrapping
+// ErrNoSecret.
+func MiddlewareWithResolver(resolve SecretResolver, opts ...MiddlewareOpt) func(http.Handler) http.Handler {
+	return middleware(resolve, false, opts...)
+}
+
+// MatchedSecret returns the index of the secret that verified the delivery,
+// as set by Middleware and MiddlewareWithResolver. The index is into the
+// list named by Delivery.SecretSource, as its resolver returned it. ok is
+// false outside a verified request. It is a shorthand for
+// DeliveryFromContext.
+func MatchedSecret(ctx context.Context) (index int, ok bool) {
+	d, ok := DeliveryFromContext(ctx)
+	return d.SecretIndex, ok
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyAny(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	body := []byte(`{"zen":"Design for failure."}`)
+
+	tests := []struct {
+		name      string
+		secrets   [][]byte
+		sig       string
+		wantIndex int
+		wantErr   error
+	}{
+		{"first secret", [][]byte{newSecret, oldSecret}, sign(newSecret, body), 0, nil},
+		{"second secret", [][]byte{newSecret, oldSecret}, sign(oldSecret, body), 1, nil},
+		{"duplicate secrets report the first", [][]byte{oldSecret, oldSecret}, sign(oldSecret, body), 0, nil},
+		{"no match", [][]byte{newSecret, oldSecret}, sign([]byte("other"), body), -1, ErrSignatureMismatch},
+		{"no secrets", nil, sign(oldSecret, body), -1, ErrNoSecret},
+		{"missing signature", [][]byte{newSecret}, "", -1, ErrMissingSignature},
+		{"malformed signature", [][]byte{newSecret}, "sha1=abcd", -1, ErrInvalidSignatureFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyAny(tt.secrets, body, tt.sig)
+			if got != tt.wantIndex || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyAny() = %d, %v, want %d, %v", got, err, tt.wantIndex, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWithResolver(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	body := []byte(`{"action":"opened"}`)
+
+	// The resolver is consulted per delivery, so swapping the secrets takes
+	// effect immediately.
+	var current atomic.Pointer[[][]byte]
+	current.Store(&[][]byte{newSecret, oldSecret})
+	resolve := func(*http.Request) ([][]byte, error) { return *current.Load(), nil }
+
+	gotIndex := -1
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIndex, _ = MatchedSecret(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := MiddlewareWithResolver(resolve)(next)
+
+	serve := func(secret []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, sign(secret, body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(oldSecret); code != http.StatusNoContent || gotIndex != 1 {
+		t.Fatalf("old secret: status = %d, index = %d, want 204, 1", code, gotIndex)
+	}
+	if code := serve(newSecret); code != http.StatusNoContent || gotIndex != 0 {
+		t.Fatalf("new secret: status = %d, index = %d, want 204, 0", code, gotIndex)
+	}
+
+	current.Store(&[][]byte{newSecret})
+	if code := serve(oldSecret); code != http.StatusUnauthorized {
+		t.Errorf("retired secret: status = %d, want 401", code)
+	}
+}
+
+func TestMiddlewareWithResolver_NoSecret(t *testing.T) {
+	body := []byte(`{}`)
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("next handler called without a verified delivery")
+	})
+
+	for name, resolve := range map[string]SecretResolver{
+		"resolver error": func(*http.Request) ([][]byte, error) { return nil, errors.New("vault sealed") },
+		"no secrets":     StaticSecrets(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, sign(nil, body))
+			rec := httptest.NewRecorder()
+
+			MiddlewareWithResolver(resolve)(next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", rec.Code)
+			}
+		})
+	}
+}
+
+// countingReader counts the reads of a request body.
+type countingReader struct {
+	r     *bytes.Reader
+	reads atomic.Int32
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads.Add(1)
+	return c.r.Read(p)
+}
+
+func TestMiddlewareWithResolver_BeforeBody(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"action":"opened"}`)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantReads bool
+	}{
+		{"resolved", nil, http.StatusNoContent, true},
+		{"resolver fails", errors.New("vault sealed"), http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &countingReader{r: bytes.NewReader(body)}
+			resolve := func(*http.Request) ([][]byte, error) {
+				if n := rc.reads.Load(); n != 0 {
+					t.Errorf("resolver called after %d body reads, want before the body is read", n)
+				}
+				return [][]byte{secret}, tt.err
+			}
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", rc)
+			req.Header.Set(SignatureHeader, sign(secret, body))
+			rec := httptest.NewRecorder()
+			MiddlewareWithResolver(resolve)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			// A delivery without a secret is rejected without reading it.
+			if read := rc.reads.Load() > 0; read != tt.wantReads {
+				t.Errorf("body read = %v, want %v", read, tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestMatchedSecret_Outside(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	if _, ok := MatchedSecret(req.Context()); ok {
+		t.Error("MatchedSecret() ok outside a verified request")
+	}
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// LegacySignatureHeader carries the HMAC-SHA1 signature GitHub sends next to
+// X-Hub-Signature-256 for compatibility. Verify does not accept it.
+const LegacySignatureHeader = "X-Hub-Signature"
+
+// defaultUserAgent is sent by NewSignedRequest when the Delivery has none.
+const defaultUserAgent = "GitHub-Hookshot/go-githubauth"
+
+// Sign returns the X-Hub-Signature-256 value GitHub would send for body:
+// "sha256=" followed by the hex HMAC-SHA256 of body keyed with secret. It is
+// the inverse of Verify.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signSHA1 returns the X-Hub-Signature value for body.
+func signSHA1(secret, body []byte) string {
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(body)
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSignedRequest returns a POST request to url carrying body the way
+// GitHub delivers it: signed with secret in both X-Hub-Signature-256 and
+// X-Hub-Signature, with the X-GitHub-Event and X-GitHub-Delivery headers,
+// the X-GitHub-Hook-* headers when d.Hook is set, a User-Agent and a JSON
+// content type. d.Event is required; an empty d.ID gets a random GUID and
+// an empty d.UserAgent a GitHub-Hookshot one. SecretIndex, SecretSource
+// and PayloadSize are ignored.
+//
+// Serve the request to a handler in tests, or send it with an http.Client to
+// forward a delivery:
+//
+//	req, err := webhook.NewSignedRequest(ctx, "/webhook", secret,
+//		webhook.Delivery{Event: "issues"}, payload)
+//	rec := httptest.NewRecorder()
+//	handler.ServeHTTP(rec, req)
+func NewSignedRequest(ctx context.Context, url string, secret []byte, d Delivery, body []byte) (*http.Request, error) {
+	if d.Event == "" {
+		return nil, errors.New("webhook: delivery event is required")
+	}
+	if d.ID == "" {
+		d.ID = newDeliveryID()
+	}
+	if d.UserAgent == "" {
+		d.UserAgent = defaultUserAgent
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", d.UserAgent)
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+	req.Header.Set(LegacySignatureHeader, signSHA1(secret, body))
+	if d.Hook != (Hook{}) {
+		req.Header.Set(HookIDHeader, strconv.FormatInt(d.Hook.ID, 10))
+		req.Header.Set(HookTargetTypeHeader, d.Hook.TargetType)
+		req.Header.Set(HookTargetIDHeader, strconv.FormatInt(d.Hook.TargetID, 10))
+	}
+	return req, nil
+}
+
+// newDeliveryID returns a random GUID in the form of X-GitHub-Delivery.
+func newDeliveryID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])  // never fails
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	secret := []byte("It's a Secret to Everybody")
+	body := []byte("Hello, World!")
+
+	// Example from GitHub's "Validating webhook deliveries" documentation.
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got := Sign(secret, body); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+	if err := Verify(secret, body, Sign(secret, body)); err != nil {
+		t.Errorf("Verify(Sign()) = %v", err)
+	}
+}
+
+func TestNewSignedRequest(t *testing.T) {
+	secret := []byte("s3cret")
+	body := readFixture(t, "issues")
+	hook := Hook{ID: 42, TargetType: "integration", TargetID: 7}
+
+	req, err := NewSignedRequest(context.Background(), "/webhook", secret,
+		Delivery{Event: "issues", ID: "d-1", Hook: hook}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{
+		EventHeader:           "issues",
+		DeliveryHeader:        "d-1",
+		SignatureHeader:       Sign(secret, body),
+		LegacySignatureHeader: signSHA1(secret, body),
+		"Content-Type":        "application/json",
+		"User-Agent":          defaultUserAgent,
+	}
+	for name, want := range headers {
+		if got := req.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	// The SHA-1 signature matches GitHub's documented example as well.
+	if got := signSHA1([]byte("It's a Secret to Everybody"), []byte("Hello, World!")); got != "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59" {
+		t.Errorf("signSHA1() = %s", got)
+	}
+
+	// The request passes the middleware stack like a real delivery.
+	var got Delivery
+	var routed bool
+	router := NewRouter()
+	router.OnFunc("issues", "opened", func(w http.ResponseWriter, r *http.Request) {
+		got, _ = DeliveryFromContext(r.Context())
+		routed = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := Middleware(nil, WithHookSecrets(func(_ context.Context, h Hook) ([][]byte, error) {
+		if h == hook {
+			return [][]byte{secret}, nil
+		}
+		return nil, nil
+	}))(router)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || !routed {
+		t.Fatalf("status = %d, routed %v", rec.Code, routed)
+	}
+	if got.ID != "d-1" || got.Hook != hook || got.PayloadSize != len(body) {
+		t.Errorf("delivery = %+v", got)
+	}
+}
+
+func TestNewSignedRequest_Defaults(t *testing.T) {
+	req, err := NewSignedRequest(context.Background(), "http://example.com/hook", nil, Delivery{Event: "ping"}, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	guid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := req.Header.Get(DeliveryHeader); !guid.MatchString(id) {
+		t.Errorf("%s = %q, want a random GUID", DeliveryHeader, id)
+	}
+	if req.Header.Get(HookIDHeader) != "" {
+		t.Errorf("%s set without a hook", HookIDHeader)
+	}
+	// The body can be replayed, e.g. by an http.Client following redirects.
+	if req.GetBody == nil || req.ContentLength != 2 {
+		t.Errorf("GetBody set %v, ContentLength = %d", req.GetBody != nil, req.ContentLength)
+	}
+	if b, _ := io.ReadAll(req.Body); string(b) != `{}` {
+		t.Errorf("body = %q", b)
+	}
+
+	if _, err := NewSignedRequest(context.Background(), "/webhook", nil, Delivery{}, nil); err == nil {
+		t.Error("NewSignedRequest() without event succeeded")
+	}
+}
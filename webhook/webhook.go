@@ -43,26 +43,35 @@ var (
 // signature must be in GitHub's "sha256=<hex>" form, as delivered in the
 // X-Hub-Signature-256 header. Comparison runs in constant time.
 func Verify(secret, body []byte, signature string) error {
+	got, err := parseSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// parseSignature decodes a "sha256=<hex>" signature header.
+func parseSignature(signature string) ([]byte, error) {
 	if signature == "" {
-		return ErrMissingSignature
+		return nil, ErrMissingSignature
 	}
 
 	hexSig, ok := strings.CutPrefix(signature, signaturePrefix)
 	if !ok {
-		return fmt.Errorf("%w: expected %q prefix", ErrInvalidSignatureFormat, signaturePrefix)
+		return nil, fmt.Errorf("%w: expected %q prefix", ErrInvalidSignatureFormat, signaturePrefix)
 	}
 
 	got, err := hex.DecodeString(hexSig)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrInvalidSignatureFormat, err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignatureFormat, err)
 	}
-
-	mac := hmac.New(sha256.New, secret)
-	mac.Write(body)
-	if !hmac.Equal(got, mac.Sum(nil)) {
-		return ErrSignatureMismatch
-	}
-	return nil
+	return got, nil
 }
 
 // MiddlewareOpt configures Middleware.
@@ -71,6 +80,9 @@ type MiddlewareOpt func(*middlewareConfig)
 type middlewareConfig struct {
 	maxPayloadSize int64
 	onError        func(http.ResponseWriter, *http.Request, error)
+	// hookSecrets, set by WithHookSecrets, resolves secrets ahead of the
+	// resolver passed to middleware.
+	hookSecrets HookSecretFunc
 }
 
 // WithMaxPayloadSize overrides the request body size cap. A non-positive value
@@ -80,7 +92,8 @@ func WithMaxPayloadSize(n int64) MiddlewareOpt {
 }
 
 // WithErrorHandler overrides how verification failures are reported. The
-// default writes 401 Unauthorized (or 413 for oversized bodies) with no body.
+// default writes 401 Unauthorized (413 for oversized bodies, 500 when no
+// secret is available) with a short plain-text body.
 func WithErrorHandler(fn func(http.ResponseWriter, *http.Request, error)) MiddlewareOpt {
 	return func(c *middlewareConfig) { c.onError = fn }
 }
@@ -88,15 +101,40 @@ func WithErrorHandler(fn func(http.ResponseWriter, *http.Request, error)) Middle
 // Middleware returns net/http middleware that verifies the signature header
 // against secret before invoking next. Failed verifications short-circuit
 // with 401 Unauthorized; bodies larger than the configured cap return 413.
-// The request body is restored for downstream handlers.
+// The request body is restored for downstream handlers, and the delivery's
+// metadata is available to them through DeliveryFromContext.
 func Middleware(secret []byte, opts ...MiddlewareOpt) func(http.Handler) http.Handler {
+	return middleware(StaticSecrets(secret), len(secret) == 0, opts...)
+}
+
+// middleware verifies deliveries with the secrets of fallback, or those of
+// the WithHookSecrets resolver when set. noFallback marks fallback as the
+// empty secret of Middleware(nil), which then does not verify deliveries of
+// unknown hooks.
+func middleware(fallback SecretResolver, noFallback bool, opts ...MiddlewareOpt) func(http.Handler) http.Handler {
 	cfg := middlewareConfig{maxPayloadSize: DefaultMaxPayloadSize}
 	for _, o := range opts {
 		o(&cfg)
 	}
+	resolve := fallbackResolver(fallback)
+	if cfg.hookSecrets != nil {
+		if noFallback {
+			fallback = nil
+		}
+		resolve = hookResolver(cfg.hookSecrets, fallback)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secrets, source, err := resolve(r)
+			if err != nil {
+				if !errors.Is(err, ErrUnknownHook) {
+					err = fmt.Errorf("%w: %w", ErrNoSecret, err)
+				}
+				handleErr(w, r, cfg.onError, err)
+				return
+			}
+
 			reader := r.Body
 			if cfg.maxPayloadSize > 0 {
 				reader = http.MaxBytesReader(w, r.Body, cfg.maxPayloadSize)
@@ -108,11 +146,13 @@ func Middleware(secret []byte, opts ...MiddlewareOpt) func(http.Handler) http.Ha
 				return
 			}
 
-			if err := Verify(secret, body, r.Header.Get(SignatureHeader)); err != nil {
+			matched, err := VerifyAny(secrets, body, r.Header.Get(SignatureHeader))
+			if err != nil {
 				handleErr(w, r, cfg.onError, err)
 				return
 			}
 
+			r = r.WithContext(withDelivery(r.Context(), newDelivery(r, matched, source, len(body))))
 			r.Body = io.NopCloser(bytes.NewReader(body))
 			r.ContentLength = int64(len(body))
 			next.ServeHTTP(w, r)
@@ -131,5 +171,9 @@ func handleErr(w http.ResponseWriter, r *http.Request, custom func(http.Response
 		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
 		return
 	}
+	if errors.Is(err, ErrNoSecret) {
+		http.Error(w, "webhook secret unavailable", http.StatusInternalServerError)
+		return
+	}
 	http.Error(w, "signature verification failed", http.StatusUnauthorized)
 }